@@ -3,59 +3,235 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
 
-
+	"github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+	"github.com/lahnasti/GO_praktikum/internal/blob"
 	"github.com/lahnasti/GO_praktikum/internal/config"
 	"github.com/lahnasti/GO_praktikum/internal/logger"
 	"github.com/lahnasti/GO_praktikum/internal/repository"
+	"github.com/lahnasti/GO_praktikum/internal/scheduler"
 	"github.com/lahnasti/GO_praktikum/internal/server"
+	"github.com/lahnasti/GO_praktikum/internal/stream"
+	"github.com/lahnasti/GO_praktikum/internal/users"
 )
 
 func main() {
 	zlog := logger.SetupLogger(true)
 	zlog.Debug().Msg("Logger was inited")
 
-	cfg := config.ReadConfig()
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		panic(err)
+	}
 	fmt.Println(cfg)
 
 	conn, err := initDB(cfg.DBAddr)
 	if err != nil {
 		panic(err)
 	}
-	
+
 	//repo := repository.New(zlog)
 	storage := repository.NewDB(conn)
-	//server := server.New(repo, zlog)
+	//comments := repository.NewComments(zlog)
+	comments := repository.NewCommentsDB(conn)
+	//timeEntries := repository.NewTimeEntries(zlog)
+	timeEntries := repository.NewTimeEntriesDB(conn)
+	//templates := repository.NewTemplates(zlog)
+	templates := repository.NewTemplatesDB(conn)
+	//views := repository.NewViews(zlog)
+	views := repository.NewViewsDB(conn)
+	//webhooks := webhook.NewStorage()
+	webhooks := webhook.NewDBStorage(conn)
+
+	blobs, err := initBlobs(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Планировщик напоминаний работает рядом с HTTP-сервером
+	notifier := scheduler.NewLogNotifier(zlog)
+	reminders := scheduler.New(&storage, notifier, cfg.ReminderInterval, cfg.ReminderLead, zlog)
+	go reminders.Run(ctx)
+
+	// Рассылка событий подписчикам вебхуков
+	events := webhook.New(&webhooks, webhook.Config{
+		Interval:    cfg.WebhookInterval,
+		Timeout:     cfg.WebhookTimeout,
+		Backoff:     cfg.WebhookBackoff,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Targets:     webhook.Targets{Hosts: cfg.WebhookHosts, AllowPrivate: cfg.WebhookAllowPrivate},
+	}, zlog)
+	go events.Run(ctx)
 
-	validate := validator.New()  // Инициализация валидатора
+	// Поток изменений задач; экземпляры сервиса обмениваются событиями через LISTEN/NOTIFY
+	//tasks := stream.NewBroker(stream.NewHub(cfg.StreamBuffer), nil, zlog)
+	tasks := stream.NewBroker(stream.NewHub(cfg.StreamBuffer), &storage, zlog)
+	go tasks.Run(ctx)
 
-	server := server.Server{
-		Db: &storage,
-		Valid: validate,
+	validate := validator.New() // Инициализация валидатора
+
+	// Авторизация включается ключом подписи JWT
+	var auth, streamAuth gin.HandlerFunc
+	if cfg.JWTSecret != "" {
+		auth = server.AuthMiddleware([]byte(cfg.JWTSecret))
+		streamAuth = server.StreamAuthMiddleware([]byte(cfg.JWTSecret))
+	}
+	adminOnly := server.AdminOnly(cfg.Admins)
+
+	server := server.New(&storage, validate, zlog)
+	server.CascadeDelete = cfg.DeleteChildren == "cascade"
+	server.Comments = &comments
+	server.TimeEntries = &timeEntries
+	server.Templates = &templates
+	server.Views = &views
+	server.Blobs = blobs
+	server.MaxAttachmentSize = cfg.MaxAttachmentSize
+	server.CalendarSecret = []byte(cfg.JWTSecret)
+	server.CalendarTokenTTL = cfg.CalendarTokenTTL
+	server.Webhooks = &webhooks
+	server.Events = events
+	server.Stream = tasks
+	if cfg.UsersAddr != "" {
+		server.Users = users.NewClient(cfg.UsersAddr, 5*time.Second)
 	}
 
+	// Очистка корзины от задач старше срока хранения. Хранилища без каскадного
+	// удаления и содержимое вложений чистятся после неё
+	purger := scheduler.NewPurger(&storage, cfg.PurgeInterval, cfg.TrashRetention, zlog)
+	purger.OnPurge(comments.PurgeTasks)
+	purger.OnPurge(timeEntries.PurgeTasks)
+	purger.OnPurge(server.PurgeAttachments)
+	go purger.Run(ctx)
+
 	r := gin.Default()
-	r.GET("/tasks", server.GetTasksHandler) 
+	// Регистрируется до авторизации: клиенты календарей не умеют отправлять JWT,
+	// лента проверяет свой токен из ссылки
+	r.GET("/calendar.ics", server.CalendarHandler)
+	// Тоже до общей авторизации: EventSource и WebSocket в браузере не отправляют
+	// заголовок Authorization, токен передаётся в ?access_token=
+	streams := r.Group("/tasks")
+	if streamAuth != nil {
+		streams.Use(streamAuth)
+	}
+	streams.GET("/stream", server.StreamTasksHandler)
+	streams.GET("/ws", server.TasksWebSocketHandler)
+	if auth != nil {
+		r.Use(auth)
+	}
+	r.GET("/tasks", server.GetTasksHandler)
+	r.GET("/tasks/actionable", server.GetActionableTasksHandler)
+	r.GET("/tasks/search", server.SearchTasksHandler)
+	r.GET("/tasks/export", server.ExportTasksHandler)
+	r.POST("/calendar/token", server.CalendarTokenHandler)
+	r.POST("/tasks/import", server.ImportTasksHandler)
 	r.POST("/tasks", server.AddTaskHandler)
 	r.GET("/tasks/:id", server.GetTaskByIDHandler)
 	r.PUT("/tasks/:id", server.UpdateTaskHandler)
+	r.PATCH("/tasks/:id", server.PatchTaskHandler)
 	r.DELETE("/tasks/:id", server.DeleteTaskHandler)
+	r.POST("/tasks/:id/transitions", server.TransitionTaskHandler)
+	r.POST("/tasks/:id/restore", server.RestoreTaskHandler)
+	r.GET("/tasks/:id/history", server.GetHistoryHandler)
+	r.GET("/tasks/:id/occurrences", server.GetOccurrencesHandler)
+	r.GET("/tasks/:id/comments", server.GetCommentsHandler)
+	r.POST("/tasks/:id/comments", server.AddCommentHandler)
+	r.PUT("/tasks/:id/comments/:comment", server.UpdateCommentHandler)
+	r.DELETE("/tasks/:id/comments/:comment", server.DeleteCommentHandler)
+	r.POST("/tasks/:id/timer/start", server.StartTimerHandler)
+	r.POST("/tasks/:id/timer/stop", server.StopTimerHandler)
+	r.GET("/tasks/:id/time", server.GetTimeEntriesHandler)
+	r.POST("/tasks/:id/time", server.AddTimeEntryHandler)
+	r.DELETE("/tasks/:id/time/:entry", server.DeleteTimeEntryHandler)
+	r.GET("/tasks/:id/attachments", server.GetAttachmentsHandler)
+	r.POST("/tasks/:id/attachments", server.UploadAttachmentHandler)
+	r.GET("/tasks/:id/attachments/:attachment", server.DownloadAttachmentHandler)
+	r.DELETE("/tasks/:id/attachments/:attachment", server.DeleteAttachmentHandler)
+	r.GET("/tasks/:id/tree", server.GetTaskTreeHandler)
+	r.POST("/tasks/:id/template", server.SaveTaskTemplateHandler)
+	r.GET("/tasks/:id/dependencies", server.GetDependenciesHandler)
+	r.POST("/tasks/:id/dependencies", server.AddDependencyHandler)
+	r.DELETE("/tasks/:id/dependencies/:dep", server.RemoveDependencyHandler)
+	r.GET("/tasks/:id/labels", server.GetTaskLabelsHandler)
+	r.POST("/tasks/:id/labels", server.AttachLabelHandler)
+	r.DELETE("/tasks/:id/labels/:label", server.DetachLabelHandler)
+
+	r.GET("/trash", server.GetTrashHandler)
+
+	r.GET("/templates", server.GetTemplatesHandler)
+	r.POST("/templates", server.AddTemplateHandler)
+	r.GET("/templates/:id", server.GetTemplateHandler)
+	r.PUT("/templates/:id", server.UpdateTemplateHandler)
+	r.DELETE("/templates/:id", server.DeleteTemplateHandler)
+	r.POST("/templates/:id/instantiate", server.InstantiateTemplateHandler)
+
+	r.GET("/views", server.GetViewsHandler)
+	r.POST("/views", server.AddViewHandler)
+	r.GET("/views/:id", server.GetViewHandler)
+	r.PUT("/views/:id", server.UpdateViewHandler)
+	r.DELETE("/views/:id", server.DeleteViewHandler)
+
+	r.GET("/timer", server.GetTimerHandler)
+	r.GET("/reports/timesheet", server.TimesheetHandler)
+
+	r.GET("/stats/tasks", server.TaskStatsHandler)
+	r.GET("/stats/burndown", server.BurndownHandler)
+	r.GET("/stats/cfd", server.CumulativeFlowHandler)
+
+	admin := r.Group("/webhooks", adminOnly)
+	admin.GET("", server.GetWebhooksHandler)
+	admin.POST("", server.AddWebhookHandler)
+	admin.GET("/:id", server.GetWebhookHandler)
+	admin.DELETE("/:id", server.DeleteWebhookHandler)
+	admin.GET("/:id/deliveries", server.GetDeliveriesHandler)
+	admin.POST("/:id/deliveries/:delivery/redeliver", server.RedeliverHandler)
+
+	r.GET("/labels", server.GetLabelsHandler)
+	r.POST("/labels", server.AddLabelHandler)
+	r.GET("/labels/:id", server.GetLabelByIDHandler)
+	r.PUT("/labels/:id", server.UpdateLabelHandler)
+	r.DELETE("/labels/:id", server.DeleteLabelHandler)
+
+	r.GET("/users/:id/tasks", server.GetUserTasksHandler)
+
+	r.GET("/projects", server.GetProjectsHandler)
+	r.POST("/projects", server.AddProjectHandler)
+	r.GET("/projects/:id", server.GetProjectHandler)
+	r.PUT("/projects/:id", server.UpdateProjectHandler)
+	r.DELETE("/projects/:id", server.DeleteProjectHandler)
+	r.GET("/projects/:id/boards", server.GetBoardsHandler)
+	r.POST("/projects/:id/boards", server.AddBoardHandler)
+	r.GET("/boards/:id", server.GetBoardHandler)
+	r.PUT("/boards/:id", server.UpdateBoardHandler)
+	r.DELETE("/boards/:id", server.DeleteBoardHandler)
+	r.POST("/boards/:id/cards/:task/move", server.MoveCardHandler)
 
 	zlog.Info().Msg("Server was started")
 
 	if err := r.Run(cfg.Addr); err != nil {
 		panic(err)
-}
+	}
 }
 
-func initDB(addr string) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(context.Background(), addr)
+func initDB(addr string) (*pgxpool.Pool, error) {
+	conn, err := pgxpool.New(context.Background(), addr)
 	if err != nil {
 		return nil, fmt.Errorf("database initialization error: %w", err)
 	}
 	return conn, nil
 }
+
+// Хранилище вложений: бакет S3, если задан адрес, иначе папка на диске
+func initBlobs(cfg config.Conifg) (blob.Store, error) {
+	if cfg.S3.Endpoint != "" {
+		return blob.NewS3(blob.S3Config(cfg.S3))
+	}
+	return blob.NewLocal(cfg.AttachmentDir)
+}
@@ -0,0 +1,21 @@
+// Пакет blob хранит содержимое вложений: в локальной папке или в бакете
+// S3-совместимого хранилища (AWS S3, MinIO и т.п.)
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Хранилище файлов. Ключ - путь через "/", например tasks/<task_id>/<id>
+type Store interface {
+	// Записывает size байт из r под ключом key, заменяя прежнее содержимое
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Открывает содержимое на чтение; закрывает вызывающий
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Удаляет содержимое. Удаление отсутствующего ключа - не ошибка
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,163 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-central-1"
+	testBucket    = "attachments"
+)
+
+// Заглушка S3: объекты в памяти, path-style адреса, запросы без верной
+// подписи AWS Signature V4 отклоняются с 403, как в настоящем S3
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	t       *testing.T
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte), types: make(map[string]string), t: t}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.signed(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(body)) != r.ContentLength {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Проверяет подпись запроса, вычисляя её заново по описанию Signature V4
+func (f *fakeS3) signed(r *http.Request) bool {
+	amzDate := r.Header.Get("X-Amz-Date")
+	date, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		return false
+	}
+	scope := date.Format("20060102") + "/" + testRegion + "/s3/aws4_request"
+	canonical := r.Method + "\n" + r.URL.EscapedPath() + "\n" + r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:UNSIGNED-PAYLOAD\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\nUNSIGNED-PAYLOAD"
+	hash := sha256.Sum256([]byte(canonical))
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{date.Format("20060102"), testRegion, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key,
+		"AWS4-HMAC-SHA256\n"+amzDate+"\n"+scope+"\n"+hex.EncodeToString(hash[:])))
+	want := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + signature
+	if got := r.Header.Get("Authorization"); got != want {
+		f.t.Logf("bad signature: got %q, want %q", got, want)
+		return false
+	}
+	return true
+}
+
+func newTestS3(t *testing.T, secret string) (*S3, *fakeS3) {
+	t.Helper()
+	fake := newFakeS3(t)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	store, err := NewS3(S3Config{
+		Endpoint:  srv.URL + "/",
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	store, fake := newTestS3(t, testSecretKey)
+	ctx := context.Background()
+	key := "tasks/1b4e28ba-2fa1-11d2-883f-0016d3cca427/report 2024.pdf"
+	content := "%PDF-1.4 test content"
+
+	if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.types[key]; got != "application/pdf" {
+		t.Errorf("stored content type = %q, want application/pdf", got)
+	}
+
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(got) != content {
+		t.Fatalf("Get = %q, %v; want %q", got, err, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	// Удаление отсутствующего ключа - не ошибка
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of missing key: %v", err)
+	}
+}
+
+func TestS3WrongSecret(t *testing.T) {
+	store, _ := newTestS3(t, "wrong-secret")
+	err := store.Put(context.Background(), "tasks/a/b", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Put with wrong secret: err = %v, want 403 error", err)
+	}
+}
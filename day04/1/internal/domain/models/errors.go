@@ -0,0 +1,63 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+)
+
+var (
+	ErrTaskNotFound = errors.New("task not found")
+	// Ошибка в параметрах запроса (сортировка, курсор, фильтры)
+	ErrInvalidQuery = errors.New("invalid query")
+	// Статус задачи изменился с момента чтения
+	ErrStatusConflict = errors.New("task status has changed")
+	// Задача изменилась с момента чтения: версия не совпадает с ожидаемой
+	ErrVersionMismatch = errors.New("version mismatch")
+	// Новая зависимость замкнула бы цикл
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	// Родительской задачи нет или она является потомком самой задачи
+	ErrInvalidParent = errors.New("invalid parent task")
+	// Задачу нельзя восстановить из корзины, пока в корзине её родитель
+	ErrParentDeleted = errors.New("parent task is in trash")
+	ErrLabelNotFound = errors.New("label not found")
+	// Метка с таким именем уже есть
+	ErrLabelExists     = errors.New("label already exists")
+	ErrCommentNotFound = errors.New("comment not found")
+	// Комментария, на который отвечают, нет среди комментариев этой задачи
+	ErrInvalidReply       = errors.New("parent comment not found on this task")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrWebhookNotFound    = webhook.ErrWebhookNotFound
+	ErrDeliveryNotFound   = webhook.ErrDeliveryNotFound
+	ErrUserNotFound       = errors.New("user not found")
+	ErrProjectNotFound    = errors.New("project not found")
+	ErrBoardNotFound      = errors.New("board not found")
+	// Проекта задачи нет
+	ErrInvalidProject = errors.New("invalid project")
+	// Соседи карточки не из её проекта или стоят не в том порядке
+	ErrInvalidPosition   = errors.New("invalid card position")
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+	ErrTemplateNotFound  = errors.New("template not found")
+	ErrViewNotFound      = errors.New("view not found")
+	// У пользователя уже идёт таймер (одновременно - не больше одного)
+	ErrTimerRunning = errors.New("another timer is already running")
+	// У пользователя нет идущего таймера по этой задаче
+	ErrTimerNotRunning = errors.New("no running timer on this task")
+	// Под именем из JWT в сервисе пользователей несколько пользователей
+	ErrAmbiguousUser = errors.New("several users have this name")
+)
+
+// Ошибка в одной из задач пакетной операции. Row - номер задачи в пакете, с нуля
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
@@ -1,7 +1,358 @@
 package models
 
+import (
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+)
+
 type Task struct {
-	ID string
-	Title string `json:"title" validate:"required"`
-	Description string `json:"description" validate:"required"`
-}
\ No newline at end of file
+	ID          string     `json:"id"`
+	Title       string     `json:"title" validate:"required"`
+	Description string     `json:"description" validate:"required"`
+	Status      TaskStatus `json:"status" validate:"omitempty,oneof=todo in_progress review done cancelled"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// Срок выполнения и время напоминания (необязательные)
+	DueAt    *time.Time `json:"due_at,omitempty"`
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+	// Родительская задача, если это подзадача
+	ParentID *string `json:"parent_id,omitempty"`
+	// Правило повторения в формате RRULE (RFC 5545), отсчитывается от DueAt.
+	// Когда задача выполнена, создаётся следующий экземпляр серии
+	Recurrence string `json:"recurrence,omitempty" validate:"omitempty,max=200"`
+	// Серия повторяющейся задачи: общий ID всех её экземпляров, а не ссылка
+	// на задачу. Выставляет сервер
+	SeriesID *string `json:"series_id,omitempty"`
+	// Исполнитель и автор задачи - пользователи сервиса пользователей
+	AssigneeID *string `json:"assignee_id,omitempty" validate:"omitempty,uuid"`
+	ReporterID *string `json:"reporter_id,omitempty" validate:"omitempty,uuid"`
+	// Проект задачи и её место в колонке доски (лексикографический ранг).
+	// Ранг выставляет сервер: при добавлении в проект задача встаёт в конец
+	ProjectID *string `json:"project_id,omitempty" validate:"omitempty,uuid"`
+	Rank      string  `json:"rank,omitempty"`
+	// Номер версии, растёт при каждом изменении задачи. Отдаётся в ETag
+	Version int64 `json:"version"`
+	// Когда задача попала в корзину. У задач вне корзины пусто
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Версия для операций без проверки версии
+const AnyVersion int64 = 0
+
+// Статус задачи. Допустимые переходы между статусами задаёт сервер
+type TaskStatus string
+
+const (
+	StatusTodo       TaskStatus = "todo"
+	StatusInProgress TaskStatus = "in_progress"
+	StatusReview     TaskStatus = "review"
+	StatusDone       TaskStatus = "done"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// Задача закрыта и больше не требует работы
+func (s TaskStatus) Closed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
+
+// Все статусы в порядке работы над задачей
+var Statuses = []TaskStatus{StatusTodo, StatusInProgress, StatusReview, StatusDone, StatusCancelled}
+
+// Параметры выборки списка задач: пагинация, сортировка и фильтры
+type TaskQuery struct {
+	Limit  int
+	Offset int
+	// Непрозрачный курсор из NextCursor предыдущей страницы.
+	// Если задан, Offset игнорируется
+	Cursor string
+	// Поле сортировки: title, created_at; минус в начале - по убыванию
+	Sort string
+
+	// Фильтры (подстрока без учёта регистра)
+	Title       string
+	Description string
+	// Фильтр по статусу: задача подходит, если её статус есть в списке
+	Status []TaskStatus
+	// Фильтр по меткам (по имени): любая из меток или, если LabelsMatchAll, все сразу
+	Labels         []string
+	LabelsMatchAll bool
+	// Фильтры по исполнителю и автору (ID пользователя)
+	AssigneeID string
+	ReporterID string
+	// Фильтр по проекту
+	ProjectID string
+	// Выражение на языке фильтров (?q=); сочетается с остальными фильтрами через AND
+	Filter filter.Expr
+}
+
+// Пользователь из сервиса пользователей
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Страница списка задач
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Вид напоминания о задаче
+type ReminderKind string
+
+const (
+	// Срок скоро наступит (наступило RemindAt или до DueAt осталось меньше заданного интервала)
+	ReminderUpcoming ReminderKind = "upcoming"
+	// Срок DueAt прошёл, а задача не закрыта
+	ReminderOverdue ReminderKind = "overdue"
+)
+
+// Напоминание, которое планировщик должен отправить
+type Reminder struct {
+	Kind ReminderKind `json:"kind"`
+	Task Task         `json:"task"`
+}
+
+// Узел дерева подзадач. Progress - процент выполнения с учётом всех потомков
+type TaskNode struct {
+	Task
+	Progress float64     `json:"progress"`
+	Children []*TaskNode `json:"children,omitempty"`
+}
+
+// Результат полнотекстового поиска. Фрагменты экранированы для HTML,
+// совпадения в них обёрнуты в <b></b>
+type SearchResult struct {
+	Task
+	Rank               float64 `json:"rank"`
+	TitleSnippet       string  `json:"title_snippet"`
+	DescriptionSnippet string  `json:"description_snippet"`
+}
+
+// Что удалено очисткой корзины. Нужно хранилищам, которые не удаляются
+// каскадом вместе с задачей
+type Purged struct {
+	TaskIDs []string
+	// Вложения удалённых задач: их содержимое ещё лежит в хранилище файлов
+	Attachments []Attachment
+}
+
+// Зависимость между задачами: TaskID нельзя начинать, пока не выполнена DependsOnID
+type Dependency struct {
+	TaskID      string `json:"task_id"`
+	DependsOnID string `json:"depends_on" validate:"required"`
+}
+
+// Метка из общего каталога, которую можно повесить на задачу
+type Label struct {
+	ID    string `json:"id"`
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+}
+
+type Project struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name" validate:"required,max=200"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Канбан-доска проекта. Колонки идут слева направо
+type Board struct {
+	ID        string        `json:"id"`
+	ProjectID string        `json:"project_id"`
+	Name      string        `json:"name" validate:"required,max=200"`
+	Columns   []BoardColumn `json:"columns" validate:"required,min=1,dive"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Колонка доски собирает задачи с перечисленными статусами.
+// Карточка, перенесённая в колонку, получает первый из них
+type BoardColumn struct {
+	Name     string       `json:"name" validate:"required,max=100"`
+	Statuses []TaskStatus `json:"statuses" validate:"required,min=1,dive,oneof=todo in_progress review done cancelled"`
+}
+
+// Куда переместить карточку: в статус To между задачами After (выше) и Before (ниже).
+// Пустые After и Before - в конец колонки. From - статус, в котором задача была прочитана
+type TaskMove struct {
+	From   TaskStatus
+	To     TaskStatus
+	After  string
+	Before string
+}
+
+// Сохранённое представление списка задач: фильтр, сортировка и колонки.
+// Владелец - пользователь из JWT; SharedWith - кому ещё оно видно
+type View struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name" validate:"required,max=200"`
+	// Выражение на языке фильтров, как в GET /tasks?q=
+	Query string `json:"query" validate:"max=2000"`
+	// Сортировка, как в GET /tasks?sort=
+	Sort string `json:"sort" validate:"omitempty,oneof=title -title created_at -created_at"`
+	// Поля задачи в ответе; пусто - все поля
+	Columns    []string  `json:"columns"`
+	SharedWith []string  `json:"shared_with" validate:"max=100,dive,required"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Шаблон задачи или дерева подзадач. В названиях и описаниях задач -
+// подстановки {{name}}, значения для них передаются при создании задач
+type Template struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name" validate:"required,max=200"`
+	Description string         `json:"description,omitempty"`
+	Tasks       []TemplateTask `json:"tasks" validate:"required,min=1,dive"`
+	// Имена подстановок из задач шаблона. Выставляет сервер
+	Variables []string  `json:"variables"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Задача шаблона с подзадачами
+type TemplateTask struct {
+	Title       string         `json:"title" validate:"required"`
+	Description string         `json:"description" validate:"required"`
+	Subtasks    []TemplateTask `json:"subtasks,omitempty" validate:"dive"`
+}
+
+// Запись учёта времени по задаче: отрезок работы пользователя.
+// Пустой EndedAt - таймер ещё идёт
+type TimeEntry struct {
+	ID        string     `json:"id"`
+	TaskID    string     `json:"task_id"`
+	User      string     `json:"user"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	// Длительность в секундах; у идущего таймера - на момент чтения
+	Seconds int64  `json:"seconds"`
+	Note    string `json:"note,omitempty"`
+	// Запись добавлена вручную, а не таймером
+	Manual bool `json:"manual"`
+}
+
+// Группировка отчёта по времени
+type TimesheetGroup string
+
+const (
+	TimesheetByTask TimesheetGroup = "task"
+	TimesheetByUser TimesheetGroup = "user"
+	// По дням (UTC) начала записи
+	TimesheetByDay TimesheetGroup = "day"
+)
+
+// Отчёт по времени: записи, начатые в [From, To), с необязательными
+// фильтрами по пользователю и задаче
+type TimesheetQuery struct {
+	From    time.Time
+	To      time.Time
+	GroupBy TimesheetGroup
+	User    string
+	TaskID  string
+}
+
+// Строка отчёта: ключ группы (ID задачи, пользователь или дата) и сумма времени
+type TimesheetRow struct {
+	Key     string `json:"key"`
+	Seconds int64  `json:"seconds"`
+	Entries int    `json:"entries"`
+}
+
+// Сводка по задачам вне корзины. Задачи без исполнителя - под ключом "none",
+// задача с несколькими метками считается в каждой
+type TaskStats struct {
+	Total      int                `json:"total"`
+	ByStatus   map[TaskStatus]int `json:"by_status"`
+	ByAssignee map[string]int     `json:"by_assignee"`
+	ByLabel    map[string]int     `json:"by_label"`
+}
+
+// Параметры статистики по дням: дни с From по To включительно (начала суток UTC)
+// и проект (пусто - все задачи)
+type FlowQuery struct {
+	From, To  time.Time
+	ProjectID string
+}
+
+// Число задач в каждом статусе на конец дня, восстановленное по журналу изменений
+type FlowPoint struct {
+	Date   string             `json:"date"`
+	Counts map[TaskStatus]int `json:"counts"`
+}
+
+// Комментарий к задаче. ParentID - комментарий, на который это ответ
+type Comment struct {
+	ID        string     `json:"id"`
+	TaskID    string     `json:"task_id"`
+	ParentID  *string    `json:"parent_id,omitempty"`
+	Author    string     `json:"author"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Комментарий с ответами - узел ветки обсуждения
+type CommentNode struct {
+	Comment
+	Replies []*CommentNode `json:"replies,omitempty"`
+}
+
+// Вложение задачи. Содержимое лежит в хранилище файлов, здесь только описание.
+// ContentType определяется по содержимому, а не по заголовку клиента
+type Attachment struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// SHA-256 содержимого в hex
+	Checksum   string    `json:"sha256"`
+	UploadedBy string    `json:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Подписка на события и доставка события подписчику. Рассылка общая
+// для сервисов day04 и живёт в day04/shared/webhook
+type (
+	Webhook  = webhook.Webhook
+	Delivery = webhook.Delivery
+)
+
+// Операция над сущностью в журнале изменений
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+	// Окончательное удаление из корзины
+	AuditPurge AuditOperation = "purge"
+)
+
+// Автор изменений, которые сервис делает сам (очистка корзины)
+const SystemActor = "system"
+
+// Запись журнала изменений. Diff содержит только изменившиеся поля
+type AuditRecord struct {
+	ID        int64          `json:"id"`
+	EntityID  string         `json:"entity_id"`
+	Actor     string         `json:"actor"`
+	Operation AuditOperation `json:"operation"`
+	At        time.Time      `json:"at"`
+	// Версия сущности после изменения
+	Version int64                  `json:"version"`
+	Diff    map[string]FieldChange `json:"diff"`
+}
+
+// Старое и новое значение поля. Для новой сущности Old пустое
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
@@ -0,0 +1,427 @@
+// Пакет filter разбирает язык фильтров задач для GET /tasks?q=, например
+//
+//	status:open AND (label:bug OR title~"login") AND due<2026-12-01
+//
+// Условие - поле, оператор и значение без пробелов между ними. Значение в кавычках
+// может содержать пробелы, \" и \\. Условия объединяются AND, OR и NOT (регистр
+// не важен), скобками и минусом перед условием (то же, что NOT). Условия подряд
+// без оператора объединяются через AND. Приоритет: NOT, затем AND, затем OR.
+//
+// Поля и операторы:
+//
+//	title, description   : - совпадает без учёта регистра, ~ - содержит подстроку
+//	status               : - статус задачи, open (не закрыта) или closed
+//	label                : - на задаче есть метка с таким именем
+//	assignee, reporter   : - ID пользователя, me или none (не назначен)
+//	project              : - ID проекта или none
+//	due, remind, created : < <= > >= - дата (весь день UTC) или момент RFC 3339;
+//	                     due:none - срок не задан
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Оператор условия
+type Op string
+
+const (
+	OpEqual        Op = ":"
+	OpContains     Op = "~"
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+)
+
+// Особые значения полей
+const (
+	// Текущий пользователь (assignee, reporter). Подставляет сервер
+	Me = "me"
+	// Поле не заполнено
+	None = "none"
+	// Статусы: любой незакрытый и любой закрытый
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// Ограничения на запрос, чтобы разбор оставался дешёвым
+const (
+	maxLength = 2000
+	maxDepth  = 32
+)
+
+// Тип значения поля определяет допустимые операторы и значения
+type kind int
+
+const (
+	kindText kind = iota
+	kindStatus
+	kindLabel
+	kindUser
+	kindID
+	kindTime
+)
+
+var fields = map[string]kind{
+	"title":       kindText,
+	"description": kindText,
+	"status":      kindStatus,
+	"label":       kindLabel,
+	"assignee":    kindUser,
+	"reporter":    kindUser,
+	"project":     kindID,
+	"due":         kindTime,
+	"remind":      kindTime,
+	"created":     kindTime,
+}
+
+var operators = map[kind][]Op{
+	kindText:   {OpEqual, OpContains},
+	kindStatus: {OpEqual},
+	kindLabel:  {OpEqual},
+	kindUser:   {OpEqual},
+	kindID:     {OpEqual},
+	kindTime:   {OpEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual},
+}
+
+var statuses = []string{"todo", "in_progress", "review", "done", "cancelled", StatusOpen, StatusClosed}
+
+// Узел дерева разбора: *And, *Or, *Not или *Cond
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+// Условие на поле задачи
+type Cond struct {
+	Field string
+	Op    Op
+	// Значение как в запросе (без кавычек)
+	Value string
+	// Значение none: поле не заполнено
+	None bool
+	// Для полей времени - интервал [From, To): дата - весь день,
+	// момент - одна микросекунда
+	From, To time.Time
+	// Позиция значения в запросе (в символах, с 1)
+	Pos int
+}
+
+func (*And) expr()  {}
+func (*Or) expr()   {}
+func (*Not) expr()  {}
+func (*Cond) expr() {}
+
+// Ошибка разбора. Pos - позиция проблемы в запросе (в символах, с 1)
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Разбирает запрос в дерево выражения
+func Parse(src string) (Expr, error) {
+	p := &parser{src: src}
+	if len(src) > maxLength {
+		return nil, p.errorf(maxLength, "query is longer than %d bytes", maxLength)
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(src) {
+		return nil, p.errorf(p.pos, "unexpected %q", p.peekRune())
+	}
+	return expr, nil
+}
+
+// Вызывает fn для каждого условия выражения; останавливается на первой ошибке
+func Walk(expr Expr, fn func(*Cond) error) error {
+	switch e := expr.(type) {
+	case *And:
+		if err := Walk(e.Left, fn); err != nil {
+			return err
+		}
+		return Walk(e.Right, fn)
+	case *Or:
+		if err := Walk(e.Left, fn); err != nil {
+			return err
+		}
+		return Walk(e.Right, fn)
+	case *Not:
+		return Walk(e.Expr, fn)
+	case *Cond:
+		return fn(e)
+	}
+	return nil
+}
+
+// Рекурсивный спуск прямо по тексту: у значений своя лексика (в датах есть ':'),
+// поэтому отдельного лексера нет. pos - смещение в байтах
+type parser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return &SyntaxError{Pos: utf8.RuneCountInString(p.src[:pos]) + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// or = and { OR and }
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.keyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+}
+
+// and = unary { [AND] unary }
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.keyword("AND") && (p.pos == len(p.src) || p.src[p.pos] == ')' || p.isKeyword("OR")) {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+// unary = (NOT | "-") unary | "(" or ")" | cond
+func (p *parser) parseUnary() (Expr, error) {
+	p.skipSpace()
+	start := p.pos
+	negate := p.keyword("NOT")
+	if !negate && p.pos < len(p.src) && p.src[p.pos] == '-' {
+		p.pos++
+		negate = true
+	}
+	if negate {
+		if err := p.enter(start); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+	if p.pos == len(p.src) {
+		return nil, p.errorf(p.pos, "unexpected end of query, expected condition")
+	}
+	switch p.src[p.pos] {
+	case '(':
+		if err := p.enter(start); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos == len(p.src) || p.src[p.pos] != ')' {
+			return nil, p.errorf(start, "unclosed \"(\"")
+		}
+		p.pos++
+		return expr, nil
+	case ')':
+		return nil, p.errorf(p.pos, "unexpected \")\"")
+	}
+	return p.parseCond()
+}
+
+func (p *parser) enter(pos int) error {
+	if p.depth++; p.depth > maxDepth {
+		return p.errorf(pos, "query is nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// cond = field op value
+func (p *parser) parseCond() (Expr, error) {
+	start := p.pos
+	for p.pos < len(p.src) && isFieldByte(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf(start, "expected condition, found %q", p.peekRune())
+	}
+	name := strings.ToLower(p.src[start:p.pos])
+	k, ok := fields[name]
+	if !ok {
+		return nil, p.errorf(start, "unknown field %q", name)
+	}
+
+	opPos := p.pos
+	op := p.parseOp()
+	if op == "" {
+		return nil, p.errorf(opPos, "expected operator after %q", name)
+	}
+	if !slices.Contains(operators[k], op) {
+		return nil, p.errorf(opPos, "operator %q is not supported for field %q", op, name)
+	}
+
+	valuePos := p.pos
+	value, quoted, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	cond := &Cond{Field: name, Op: op, Value: value, Pos: utf8.RuneCountInString(p.src[:valuePos]) + 1}
+	if err := p.checkValue(cond, k, quoted, valuePos); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+func (p *parser) parseOp() Op {
+	for _, op := range []Op{OpLessEqual, OpGreaterEqual, OpEqual, OpContains, OpLess, OpGreater} {
+		if strings.HasPrefix(p.src[p.pos:], string(op)) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+// Значение в кавычках или до пробела либо скобки. quoted - значение было в кавычках
+func (p *parser) parseValue() (string, bool, error) {
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.src); p.pos++ {
+			switch c := p.src[p.pos]; {
+			case c == '"':
+				p.pos++
+				return b.String(), true, nil
+			case c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '"' || p.src[p.pos+1] == '\\'):
+				p.pos++
+				b.WriteByte(p.src[p.pos])
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", false, p.errorf(start, "unterminated string")
+	}
+	for p.pos < len(p.src) && !isDelimiter(p.src[p.pos]) && p.src[p.pos] != '"' {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", false, p.errorf(start, "expected value")
+	}
+	return p.src[start:p.pos], false, nil
+}
+
+// Проверяет значение по типу поля и заполняет None, From и To
+func (p *parser) checkValue(cond *Cond, k kind, quoted bool, pos int) error {
+	cond.None = !quoted && cond.Value == None
+	if cond.None && (k != kindUser && k != kindID && k != kindTime || cond.Op != OpEqual) {
+		return p.errorf(pos, "%q is not allowed here", None)
+	}
+	switch {
+	case cond.None, k == kindText, k == kindLabel:
+	case k == kindStatus:
+		if !slices.Contains(statuses, cond.Value) {
+			return p.errorf(pos, "unknown status %q", cond.Value)
+		}
+	case k == kindUser && !quoted && cond.Value == Me:
+	case k == kindUser, k == kindID:
+		if _, err := uuid.Parse(cond.Value); err != nil {
+			return p.errorf(pos, "invalid id %q", cond.Value)
+		}
+	case k == kindTime:
+		if t, err := time.Parse(time.DateOnly, cond.Value); err == nil {
+			cond.From, cond.To = t, t.AddDate(0, 0, 1)
+		} else if t, err := time.Parse(time.RFC3339, cond.Value); err == nil {
+			cond.From, cond.To = t.UTC(), t.UTC().Add(time.Microsecond)
+		} else {
+			return p.errorf(pos, "invalid time %q, expected a date (YYYY-MM-DD) or RFC 3339", cond.Value)
+		}
+	}
+	return nil
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// Ключевое слово (без учёта регистра), за которым идёт разделитель. Если есть - пропускает его
+func (p *parser) keyword(word string) bool {
+	if !p.isKeyword(word) {
+		return false
+	}
+	p.pos += len(word)
+	return true
+}
+
+func (p *parser) isKeyword(word string) bool {
+	end := p.pos + len(word)
+	return end <= len(p.src) && strings.EqualFold(p.src[p.pos:end], word) &&
+		(end == len(p.src) || isDelimiter(p.src[end]))
+}
+
+func (p *parser) peekRune() rune {
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return r
+}
+
+func isFieldByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDelimiter(c byte) bool {
+	return isSpace(c) || c == '(' || c == ')'
+}
@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Дерево в виде строки со скобками вокруг каждого AND и OR
+func render(expr Expr) string {
+	switch e := expr.(type) {
+	case *And:
+		return "(" + render(e.Left) + " AND " + render(e.Right) + ")"
+	case *Or:
+		return "(" + render(e.Left) + " OR " + render(e.Right) + ")"
+	case *Not:
+		return "NOT " + render(e.Expr)
+	case *Cond:
+		if e.None {
+			return e.Field + string(e.Op) + "<none>"
+		}
+		return e.Field + string(e.Op) + e.Value
+	}
+	return "?"
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"status:open", "status:open"},
+		// Приоритет: NOT, затем AND, затем OR
+		{"status:open OR label:bug AND title~x", "(status:open OR (label:bug AND title~x))"},
+		{"label:bug AND title~x OR status:open", "((label:bug AND title~x) OR status:open)"},
+		{"NOT status:done AND label:bug", "(NOT status:done AND label:bug)"},
+		{"NOT (status:done OR label:bug)", "NOT (status:done OR label:bug)"},
+		{"-label:bug -label:ui", "(NOT label:bug AND NOT label:ui)"},
+		{"NOT NOT label:bug", "NOT NOT label:bug"},
+		// Условия подряд без оператора - AND, слева направо
+		{"label:bug title~x status:open", "((label:bug AND title~x) AND status:open)"},
+		{"(status:open OR label:bug) title:x", "((status:open OR label:bug) AND title:x)"},
+		{"label:a label:b OR label:c", "((label:a AND label:b) OR label:c)"},
+		// Ключевые слова без учёта регистра и только целым словом
+		{"label:a or label:b and not label:c", "(label:a OR (label:b AND NOT label:c))"},
+		{"label:ORDER", "label:ORDER"},
+		{"label:a\tOR\nlabel:b", "(label:a OR label:b)"},
+		// Кавычки: пробелы, скобки и экранирование внутри значения
+		{`title:"a (b) \"c\" \\"`, `title:a (b) "c" \`},
+		{`title~"OR"`, "title~OR"},
+		{"TITLE:x", "title:x"},
+		{"due:none assignee:none project:none", "((due:<none> AND assignee:<none>) AND project:<none>)"},
+		{"assignee:me", "assignee:me"},
+		{"due>=2024-03-01T10:00:00+03:00", "due>=2024-03-01T10:00:00+03:00"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		if got := render(expr); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"", 1, "unexpected end of query"},
+		{"foo:bar", 1, `unknown field "foo"`},
+		{"status:opne", 8, `unknown status "opne"`},
+		{"status~open", 7, `operator "~" is not supported`},
+		{"title", 6, `expected operator after "title"`},
+		{"title:", 7, "expected value"},
+		{`title:"abc`, 7, "unterminated string"},
+		{"status:open AND", 16, "unexpected end of query"},
+		{"status:open OR", 15, "unexpected end of query"},
+		{"(status:open", 1, `unclosed "("`},
+		{"label:a (status:open", 9, `unclosed "("`},
+		{"status:open)", 12, `unexpected ')'`},
+		{"()", 2, `unexpected ")"`},
+		{"NOT", 4, "unexpected end of query"},
+		{`assignee:"me"`, 10, `invalid id "me"`},
+		{"assignee:bob", 10, `invalid id "bob"`},
+		{`due:"none"`, 5, `invalid time "none"`},
+		{"due:2024-13-01", 5, `invalid time "2024-13-01"`},
+		{"title~none", 7, `"none" is not allowed here`},
+		{"due<none", 5, `"none" is not allowed here`},
+		{"label:a ORtitle:x", 9, `unknown field "ortitle"`},
+		// Позиции в символах, а не в байтах: кириллица занимает по два байта
+		{`title:"привет" статус:x`, 16, "expected condition"},
+		{`title:"ё" due<2024-13-01`, 15, `invalid time "2024-13-01"`},
+		{`title~"ёж" )`, 12, `unexpected ')'`},
+		{`(title:"ёж"`, 1, `unclosed "("`},
+		{"label:ёж foo:x", 10, `unknown field "foo"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q): err = %v, want *SyntaxError", tt.src, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = %q at %d, want %q at %d", tt.src, syntaxErr.Msg, syntaxErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	deep := strings.Repeat("(", maxDepth+1) + "label:a" + strings.Repeat(")", maxDepth+1)
+	_, err := Parse(deep)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || !strings.Contains(syntaxErr.Msg, "nested deeper") || syntaxErr.Pos != maxDepth+1 {
+		t.Errorf("Parse(%d parens) = %v, want nesting error at %d", maxDepth+1, err, maxDepth+1)
+	}
+	ok := strings.Repeat("(", maxDepth) + "label:a" + strings.Repeat(")", maxDepth)
+	if _, err := Parse(ok); err != nil {
+		t.Errorf("Parse(%d parens): %v", maxDepth, err)
+	}
+	if _, err := Parse(strings.Repeat("-", maxDepth+1) + "label:a"); err == nil {
+		t.Errorf("Parse(%d minuses) succeeded, want nesting error", maxDepth+1)
+	}
+	if _, err := Parse("title~" + strings.Repeat("x", maxLength)); err == nil {
+		t.Error("Parse of a too long query succeeded")
+	}
+}
+
+func TestCondPosAndInterval(t *testing.T) {
+	expr, err := Parse(`title~"ёж" due<2024-03-01 created:2024-03-01T10:00:00+03:00`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conds []*Cond
+	Walk(expr, func(cond *Cond) error {
+		conds = append(conds, cond)
+		return nil
+	})
+	if len(conds) != 3 {
+		t.Fatalf("Walk found %d conditions, want 3", len(conds))
+	}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	moment := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	tests := []struct {
+		pos      int
+		from, to time.Time
+	}{
+		{7, time.Time{}, time.Time{}},
+		{16, day, day.AddDate(0, 0, 1)},
+		{35, moment, moment.Add(time.Microsecond)},
+	}
+	for i, tt := range tests {
+		cond := conds[i]
+		if cond.Pos != tt.pos || !cond.From.Equal(tt.from) || !cond.To.Equal(tt.to) {
+			t.Errorf("%s: pos %d [%v, %v), want pos %d [%v, %v)", cond.Field, cond.Pos, cond.From, cond.To, tt.pos, tt.from, tt.to)
+		}
+	}
+}
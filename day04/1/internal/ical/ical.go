@@ -0,0 +1,94 @@
+// Пакет ical пишет календари в формате iCalendar (RFC 5545): компоненты
+// VEVENT и VTODO со свойствами, экранированием текста и переносом длинных строк
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Длина строки в октетах, после которой строка переносится (RFC 5545, 3.1)
+const maxLineOctets = 75
+
+// Свойство компонента: имя и уже готовое значение
+type Property struct {
+	Name  string
+	Value string
+}
+
+// Компонент календаря (VEVENT, VTODO) со свойствами в порядке записи
+type Component struct {
+	Kind       string
+	Properties []Property
+}
+
+// Добавляет текстовое свойство, экранируя значение
+func (c *Component) Text(name, value string) {
+	c.Properties = append(c.Properties, Property{Name: name, Value: escapeText(value)})
+}
+
+// Добавляет свойство-момент времени в UTC
+func (c *Component) Time(name string, t time.Time) {
+	c.Properties = append(c.Properties, Property{Name: name, Value: FormatTime(t)})
+}
+
+// Добавляет свойство со значением без экранирования (числа, перечисления)
+func (c *Component) Raw(name, value string) {
+	c.Properties = append(c.Properties, Property{Name: name, Value: value})
+}
+
+// Момент времени в формате DATE-TIME UTC: 20240131T090000Z
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Пишет VCALENDAR с компонентами. prodID - идентификатор программы (PRODID), name - имя календаря
+func Write(w io.Writer, prodID, name string, components []Component) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	if name != "" {
+		line("X-WR-CALNAME", escapeText(name))
+	}
+	for _, c := range components {
+		line("BEGIN", c.Kind)
+		for _, p := range c.Properties {
+			line(p.Name, p.Value)
+		}
+		line("END", c.Kind)
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// Экранирование значения типа TEXT (RFC 5545, 3.3.11)
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// Пишет строку, перенося её по 75 октетов: продолжение начинается с пробела.
+// Многобайтовые символы UTF-8 не разрываются
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Пробел в начале продолжения тоже занимает октет
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
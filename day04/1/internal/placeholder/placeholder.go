@@ -0,0 +1,51 @@
+// Пакет placeholder подставляет значения в текст с подстановками вида {{name}}.
+// Пробелы внутри скобок допускаются: {{ name }}
+package placeholder
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var pattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// Имена подстановок из текстов: без повторов, по алфавиту
+func Names(texts ...string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, text := range texts {
+		for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+			if name := match[1]; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Имена из names, для которых в values нет значения
+func Missing(names []string, values map[string]string) []string {
+	var missing []string
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Заменяет подстановки значениями из values. Подстановки без значения остаются как есть
+func Fill(text string, values map[string]string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := values[pattern.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
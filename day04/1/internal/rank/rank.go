@@ -0,0 +1,200 @@
+// Пакет rank выдаёт лексикографические ранги для ручного порядка карточек:
+// между любыми двумя рангами всегда найдётся третий, поэтому перемещение
+// карточки меняет только её собственный ранг.
+//
+// Ранг состоит из целой части и дробной. Первый символ целой части задаёт
+// её длину ('a'..'z' - положительные, 'A'..'Z' - отрицательные числа),
+// поэтому добавление в начало или конец списка увеличивает длину ранга
+// логарифмически, а не линейно. Вставка между соседями делит дробную часть
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+// Цифры ранга в порядке возрастания байтов. В Postgres ранги сравниваются с COLLATE "C"
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const zero byte = '0'
+
+// Наименьшая целая часть: перед ней можно вставлять только дробные ранги
+var smallestInteger = "A" + strings.Repeat(string(zero), 26)
+
+var (
+	ErrOrder   = errors.New("rank: lower bound must be less than upper bound")
+	ErrInvalid = errors.New("rank: invalid rank")
+)
+
+// Between возвращает ранг строго между a и b. Пустой a - в начало списка,
+// пустой b - в конец
+func Between(a, b string) (string, error) {
+	if a != "" && !valid(a) || b != "" && !valid(b) {
+		return "", ErrInvalid
+	}
+	if a != "" && b != "" && a >= b {
+		return "", ErrOrder
+	}
+	switch {
+	case a == "" && b == "":
+		return "a" + string(zero), nil
+	case a == "":
+		ib := integerPart(b)
+		if ib == smallestInteger {
+			return ib + midpoint("", b[len(ib):]), nil
+		}
+		if ib < b {
+			return ib, nil
+		}
+		if r, ok := decrement(ib); ok {
+			return r, nil
+		}
+		return "", ErrInvalid
+	case b == "":
+		ia := integerPart(a)
+		if r, ok := increment(ia); ok {
+			return r, nil
+		}
+		return ia + midpoint(a[len(ia):], ""), nil
+	}
+	ia, ib := integerPart(a), integerPart(b)
+	if ia == ib {
+		return ia + midpoint(a[len(ia):], b[len(ib):]), nil
+	}
+	if r, ok := increment(ia); ok && r < b {
+		return r, nil
+	}
+	return ia + midpoint(a[len(ia):], ""), nil
+}
+
+// Ранг в конец списка после a (пустой a - список пуст)
+func After(a string) (string, error) {
+	return Between(a, "")
+}
+
+// Дробная часть строго между a и b (пустой b - до конца). Ни a, ни b
+// не оканчиваются нулём
+func midpoint(a, b string) string {
+	if b != "" {
+		// Общий префикс (a дополняется нулями) переносится как есть
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := len(digits)
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(digits[(lo+hi+1)/2])
+	}
+	// Соседние цифры: b с хвостом больше своей первой цифры, иначе идём на разряд глубже
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[lo]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return zero
+}
+
+// Длина целой части (с первым символом) по её первому символу; 0 - символ неверный
+func integerLength(head byte) int {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2
+	}
+	return 0
+}
+
+// Вызывается только для проверенных valid рангов
+func integerPart(r string) string {
+	return r[:integerLength(r[0])]
+}
+
+func valid(r string) bool {
+	n := integerLength(r[0])
+	if n == 0 || len(r) < n || r == smallestInteger {
+		return false
+	}
+	for i := 1; i < len(r); i++ {
+		if strings.IndexByte(digits, r[i]) < 0 {
+			return false
+		}
+	}
+	return len(r) == n || r[len(r)-1] != zero
+}
+
+// Следующее целое. false - целые кончились (после "zzz...z")
+func increment(x string) (string, bool) {
+	head, digs := x[0], []byte(x[1:])
+	for i := len(digs) - 1; i >= 0; i-- {
+		if d := strings.IndexByte(digits, digs[i]) + 1; d < len(digits) {
+			digs[i] = digits[d]
+			return string(head) + string(digs), true
+		}
+		digs[i] = zero
+	}
+	// Перенос: целая часть становится длиннее (или короче для отрицательных)
+	switch head {
+	case 'Z':
+		return "a" + string(zero), true
+	case 'z':
+		return "", false
+	}
+	head++
+	if head > 'a' {
+		digs = append(digs, zero)
+	} else {
+		digs = digs[:len(digs)-1]
+	}
+	return string(head) + string(digs), true
+}
+
+// Предыдущее целое. false - целые кончились (перед "A00...0")
+func decrement(x string) (string, bool) {
+	head, digs := x[0], []byte(x[1:])
+	last := digits[len(digits)-1]
+	for i := len(digs) - 1; i >= 0; i-- {
+		if d := strings.IndexByte(digits, digs[i]) - 1; d >= 0 {
+			digs[i] = digits[d]
+			return string(head) + string(digs), true
+		}
+		digs[i] = last
+	}
+	switch head {
+	case 'a':
+		return "Z" + string(last), true
+	case 'A':
+		return "", false
+	}
+	head--
+	if head < 'Z' {
+		digs = append(digs, last)
+	} else {
+		digs = digs[:len(digs)-1]
+	}
+	return string(head) + string(digs), true
+}
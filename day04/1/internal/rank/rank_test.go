@@ -0,0 +1,167 @@
+package rank
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Наибольшая целая часть: после неё можно добавлять только дробные ранги
+var largestInteger = "z" + strings.Repeat("z", 26)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", "", "a0"},
+		{"a0", "", "a1"},
+		{"az", "", "b00"},
+		{"Zz", "", "a0"},
+		{"", "a0", "Zz"},
+		{"", "b00", "az"},
+		{"", "Z0", "Yzz"},
+		{"a0", "a2", "a1"},
+		{"a0", "a1", "a0V"},
+		{"a0", "a0V", "a0G"},
+		{"a0z", "a1", "a0zV"},
+		{"az", "b00", "azV"},
+		{"Zz", "a0", "ZzV"},
+		// Целая часть b меньше самого b: она и есть ранг перед ним
+		{"", "a1V", "a1"},
+		{"a0V", "a1", "a0l"},
+		{"", smallestInteger + "1", smallestInteger + "0V"},
+		{largestInteger, "", largestInteger + "V"},
+	}
+	for _, tt := range tests {
+		got, err := Between(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("Between(%q, %q) = %q, %v, want %q", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+}
+
+func TestBetweenErrors(t *testing.T) {
+	tests := []struct {
+		a, b string
+		err  error
+	}{
+		{"a1", "a0", ErrOrder},
+		{"a1", "a1", ErrOrder},
+		{"x", "", ErrInvalid},
+		{"a10", "", ErrInvalid},
+		{"", "a!", ErrInvalid},
+		{"", "0", ErrInvalid},
+		{smallestInteger, "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := Between(tt.a, tt.b); !errors.Is(err, tt.err) {
+			t.Errorf("Between(%q, %q): err = %v, want %v", tt.a, tt.b, err, tt.err)
+		}
+	}
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	tests := []struct {
+		x, next string
+	}{
+		{"a0", "a1"},
+		{"a9", "aA"},
+		{"az", "b00"},
+		{"bzz", "c000"},
+		{"Z0", "Z1"},
+		{"Zz", "a0"},
+		{"Yzz", "Z0"},
+		{"Xzzz", "Y00"},
+	}
+	for _, tt := range tests {
+		if got, ok := increment(tt.x); !ok || got != tt.next {
+			t.Errorf("increment(%q) = %q, %v, want %q", tt.x, got, ok, tt.next)
+		}
+		if got, ok := decrement(tt.next); !ok || got != tt.x {
+			t.Errorf("decrement(%q) = %q, %v, want %q", tt.next, got, ok, tt.x)
+		}
+	}
+	if _, ok := increment(largestInteger); ok {
+		t.Errorf("increment(%q) succeeded", largestInteger)
+	}
+	if _, ok := decrement(smallestInteger); ok {
+		t.Errorf("decrement(%q) succeeded", smallestInteger)
+	}
+}
+
+// Каждый следующий ранг верный и стоит строго по нужную сторону от предыдущего
+func checkSequence(t *testing.T, name string, ranks []string, ascending bool) {
+	for i, r := range ranks {
+		if !valid(r) {
+			t.Fatalf("%s: rank %d %q is invalid", name, i, r)
+		}
+		if i > 0 && (ranks[i-1] < r) != ascending {
+			t.Fatalf("%s: rank %d %q is out of order after %q", name, i, r, ranks[i-1])
+		}
+	}
+}
+
+func TestRepeatedInsert(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		// Следующий ранг по предыдущему
+		next      func(prev string) (string, error)
+		ascending bool
+		// Наибольшая допустимая длина последнего ранга
+		maxLen int
+	}{
+		{"prepend", "a0", func(prev string) (string, error) { return Between("", prev) }, false, 4},
+		{"append", "a0", func(prev string) (string, error) { return Between(prev, "") }, true, 4},
+		// У краёв целых чисел растёт только дробная часть, на разряд за несколько вставок
+		{"prepend before smallest", smallestInteger + "1", func(prev string) (string, error) { return Between("", prev) }, false, 250},
+		{"append after largest", largestInteger, After, true, 250},
+		// Снова и снова между одними соседями: новый ранг становится одним из них
+		{"toward lower", "b00", func(prev string) (string, error) { return Between("a0", prev) }, false, 1100},
+		{"toward upper", "a0", func(prev string) (string, error) { return Between(prev, "b00") }, true, 200},
+		{"toward lower fraction", "a1", func(prev string) (string, error) { return Between("a0z", prev) }, false, 1100},
+	}
+	for _, tt := range tests {
+		ranks := []string{tt.start}
+		for i := 0; i < 1000; i++ {
+			r, err := tt.next(ranks[len(ranks)-1])
+			if err != nil {
+				t.Fatalf("%s: step %d: %v", tt.name, i, err)
+			}
+			ranks = append(ranks, r)
+		}
+		checkSequence(t, tt.name, ranks, tt.ascending)
+		if last := ranks[len(ranks)-1]; len(last) > tt.maxLen {
+			t.Errorf("%s: last rank %q is longer than %d", tt.name, last, tt.maxLen)
+		}
+	}
+}
+
+// Вставки в случайные места списка сохраняют строгий порядок
+func TestRandomInsert(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ranks := []string{}
+	for i := 0; i < 2000; i++ {
+		pos := rnd.Intn(len(ranks) + 1)
+		a, b := "", ""
+		if pos > 0 {
+			a = ranks[pos-1]
+		}
+		if pos < len(ranks) {
+			b = ranks[pos]
+		}
+		r, err := Between(a, b)
+		if err != nil {
+			t.Fatalf("Between(%q, %q): %v", a, b, err)
+		}
+		if !valid(r) || a != "" && r <= a || b != "" && r >= b {
+			t.Fatalf("Between(%q, %q) = %q", a, b, r)
+		}
+		ranks = append(ranks[:pos], append([]string{r}, ranks[pos:]...)...)
+	}
+	if !sort.StringsAreSorted(ranks) {
+		t.Error("ranks are not sorted")
+	}
+}
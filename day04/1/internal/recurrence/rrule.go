@@ -0,0 +1,318 @@
+// Пакет recurrence разбирает и разворачивает правила повторения задач -
+// подмножество RRULE из RFC 5545: FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY, COUNT, UNTIL
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// День недели из BYDAY. N - номер дня в месяце (1 - первый, -1 - последний),
+// 0 - каждый такой день. Номер допустим только для MONTHLY
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+// Правило повторения. Первое повторение - сама дата начала (DTSTART), как в RFC 5545
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []Weekday
+	// Сколько всего повторений, считая первое. 0 - без ограничения
+	Count int
+	// Последний допустимый момент повторения
+	Until *time.Time
+}
+
+// Сколько периодов (дней, недель, месяцев) перебирать без единого повторения,
+// прежде чем сдаться. Защищает от правил, которые почти никогда не срабатывают
+const maxEmptyPeriods = 1000
+
+// Сколько периодов перебирать всего: ежедневное правило - это около 270 лет.
+// Ограничивает работу, когда интересующий интервал далеко от начала серии
+const maxPeriods = 100000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Разбирает правило вида FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10.
+// Префикс RRULE: допускается
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	rule := Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		key = strings.ToUpper(key)
+		if seen[key] {
+			return Rule{}, fmt.Errorf("%w: duplicate %s", ErrInvalidRule, key)
+		}
+		seen[key] = true
+		value = strings.ToUpper(value)
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return Rule{}, fmt.Errorf("%w: unsupported FREQ %s", ErrInvalidRule, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRule)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("%w: COUNT must be a positive integer", ErrInvalidRule)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := parseWeekday(v)
+				if err != nil {
+					return Rule{}, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		default:
+			return Rule{}, fmt.Errorf("%w: unsupported part %s", ErrInvalidRule, key)
+		}
+	}
+	if rule.Freq == "" {
+		return Rule{}, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return Rule{}, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+	if rule.Freq != Monthly {
+		for _, day := range rule.ByDay {
+			if day.N != 0 {
+				return Rule{}, fmt.Errorf("%w: numbered BYDAY is only allowed with FREQ=MONTHLY", ErrInvalidRule)
+			}
+		}
+	}
+	return rule, nil
+}
+
+// UNTIL в формате даты-времени UTC (20240131T090000Z) или даты (20240131) -
+// тогда повторения допускаются до конца этого дня
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ", ErrInvalidRule)
+}
+
+func parseWeekday(v string) (Weekday, error) {
+	if len(v) < 2 {
+		return Weekday{}, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRule, v)
+	}
+	day, ok := weekdays[v[len(v)-2:]]
+	if !ok {
+		return Weekday{}, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRule, v)
+	}
+	var n int
+	if prefix := v[:len(v)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return Weekday{}, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRule, v)
+		}
+	}
+	return Weekday{N: n, Day: day}, nil
+}
+
+// Правило в каноническом виде, который понимает Parse
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = strings.ToUpper(day.Day.String()[:2])
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Повторения с началом start, попадающие в [from, to], не больше limit штук
+func (r Rule) Between(start, from, to time.Time, limit int) []time.Time {
+	var dates []time.Time
+	r.each(start, func(t time.Time) bool {
+		if t.After(to) || len(dates) >= limit {
+			return false
+		}
+		if !t.Before(from) {
+			dates = append(dates, t)
+		}
+		return true
+	})
+	return dates
+}
+
+// Следующее повторение после start и правило для него: для следующего экземпляра
+// серии он сам становится началом, поэтому COUNT уменьшается на один.
+// false, если повторений больше нет
+func (r Rule) Next(start time.Time) (time.Time, Rule, bool) {
+	var next time.Time
+	found := false
+	r.each(start, func(t time.Time) bool {
+		if t.After(start) {
+			next, found = t, true
+			return false
+		}
+		return true
+	})
+	if !found {
+		return time.Time{}, Rule{}, false
+	}
+	if r.Count > 0 {
+		r.Count--
+	}
+	return next, r, true
+}
+
+// Перебирает повторения по возрастанию, пока fn возвращает true,
+// с учётом COUNT и UNTIL, но не дальше maxPeriods периодов от start
+func (r Rule) each(start time.Time, fn func(time.Time) bool) {
+	n := 0
+	emit := func(t time.Time) bool {
+		if r.Until != nil && t.After(*r.Until) {
+			return false
+		}
+		if r.Count > 0 && n >= r.Count {
+			return false
+		}
+		n++
+		return fn(t)
+	}
+	if !emit(start) {
+		return
+	}
+	for period, empty := 0, 0; empty < maxEmptyPeriods && period < maxPeriods; period++ {
+		candidates := r.period(start, period)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, t := range candidates {
+			if t.After(start) && !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// Кандидаты в повторения в period-м периоде от начала (0 - период самого start),
+// по возрастанию. Время суток берётся из start
+func (r Rule) period(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, start.Nanosecond(), start.Location())
+	}
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+step)
+		if len(r.ByDay) > 0 && !r.hasDay(t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(y, m, d+7*step)}
+		}
+		// Неделя начинается с понедельника (WKST=MO по умолчанию)
+		monday := d - (int(start.Weekday())+6)%7 + 7*step
+		var dates []time.Time
+		for offset := 0; offset < 7; offset++ {
+			t := at(y, m, monday+offset)
+			if r.hasDay(t.Weekday()) {
+				dates = append(dates, t)
+			}
+		}
+		return dates
+	case Monthly:
+		first := at(y, m+time.Month(step), 1)
+		if len(r.ByDay) == 0 {
+			// Месяцы без такого числа пропускаются (RFC 5545)
+			t := at(first.Year(), first.Month(), d)
+			if t.Month() != first.Month() {
+				return nil
+			}
+			return []time.Time{t}
+		}
+		return r.monthDays(first, at)
+	}
+	return nil
+}
+
+// Дни месяца (first - его первое число), подходящие под BYDAY, по возрастанию
+func (r Rule) monthDays(first time.Time, at func(int, time.Month, int) time.Time) []time.Time {
+	var dates []time.Time
+	days := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for day := 1; day <= days; day++ {
+		t := at(first.Year(), first.Month(), day)
+		fromStart := (day-1)/7 + 1
+		fromEnd := -((days-day)/7 + 1)
+		for _, wd := range r.ByDay {
+			if wd.Day == t.Weekday() && (wd.N == 0 || wd.N == fromStart || wd.N == fromEnd) {
+				dates = append(dates, t)
+				break
+			}
+		}
+	}
+	return dates
+}
+
+func (r Rule) hasDay(day time.Weekday) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package recurrence
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Дата в 09:00 UTC
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(9 * time.Hour)
+}
+
+func dates(times []time.Time) []string {
+	out := []string{}
+	for _, t := range times {
+		out = append(out, t.Format("2006-01-02"))
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=mo,we;interval=2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{"FREQ=DAILY;INTERVAL=1;COUNT=3", "FREQ=DAILY;COUNT=3"},
+		{"FREQ=MONTHLY;BYDAY=1MO,-1FR", "FREQ=MONTHLY;BYDAY=1MO,-1FR"},
+		// Дата без времени - до конца этого дня
+		{"FREQ=DAILY;UNTIL=20240131", "FREQ=DAILY;UNTIL=20240131T235959Z"},
+		{"FREQ=DAILY;UNTIL=20240131T090000Z", "FREQ=DAILY;UNTIL=20240131T090000Z"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.src, got, tt.want)
+		}
+		again, err := Parse(rule.String())
+		if err != nil || !reflect.DeepEqual(again, rule) {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", rule.String(), again, err, rule)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"FREQ=DAILY;",
+		"FREQ=YEARLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+		"FREQ=DAILY;UNTIL=2024-01-01",
+		"FREQ=DAILY;BYMONTH=1",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYDAY=0MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+	}
+	for _, src := range tests {
+		if _, err := Parse(src); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q): err = %v, want ErrInvalidRule", src, err)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		start    string
+		from, to string
+		limit    int
+		want     []string
+	}{
+		// Начало серии - всегда первое повторение, даже не в день из BYDAY
+		{"weekly from midweek", "FREQ=WEEKLY;BYDAY=MO,FR", "2024-01-03", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-03", "2024-01-05", "2024-01-08", "2024-01-12"}},
+		{"weekly every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2024-01-03", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-03", "2024-01-05", "2024-01-15", "2024-01-19"}},
+		{"weekly without byday", "FREQ=WEEKLY", "2024-01-03", "2024-01-01", "2024-12-31", 3,
+			[]string{"2024-01-03", "2024-01-10", "2024-01-17"}},
+		{"daily weekends", "FREQ=DAILY;BYDAY=SA,SU", "2024-01-05", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-05", "2024-01-06", "2024-01-07", "2024-01-13"}},
+		// Первая неделя месяца: начало серии само первый понедельник
+		{"first monday", "FREQ=MONTHLY;BYDAY=1MO", "2024-01-01", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-01", "2024-02-05", "2024-03-04", "2024-04-01"}},
+		{"first sunday", "FREQ=MONTHLY;BYDAY=1SU", "2024-01-07", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-07", "2024-02-04", "2024-03-03", "2024-04-07"}},
+		{"last friday", "FREQ=MONTHLY;BYDAY=-1FR", "2024-01-26", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-26", "2024-02-23", "2024-03-29", "2024-04-26"}},
+		// Месяцы без 31-го числа пропускаются
+		{"end of month", "FREQ=MONTHLY", "2024-01-31", "2024-01-01", "2024-12-31", 4,
+			[]string{"2024-01-31", "2024-03-31", "2024-05-31", "2024-07-31"}},
+		{"leap day", "FREQ=MONTHLY;INTERVAL=12", "2024-02-29", "2024-01-01", "2032-12-31", 10,
+			[]string{"2024-02-29", "2028-02-29", "2032-02-29"}},
+		{"count", "FREQ=DAILY;COUNT=3", "2024-01-01", "2024-01-01", "2024-12-31", 10,
+			[]string{"2024-01-01", "2024-01-02", "2024-01-03"}},
+		// COUNT считается от начала серии, а не от from
+		{"count before window", "FREQ=DAILY;COUNT=3", "2024-01-01", "2024-01-03", "2024-12-31", 10,
+			[]string{"2024-01-03"}},
+		{"until date", "FREQ=DAILY;UNTIL=20240103", "2024-01-01", "2024-01-01", "2024-12-31", 10,
+			[]string{"2024-01-01", "2024-01-02", "2024-01-03"}},
+		{"until time", "FREQ=DAILY;UNTIL=20240103T080000Z", "2024-01-01", "2024-01-01", "2024-12-31", 10,
+			[]string{"2024-01-01", "2024-01-02"}},
+		{"window", "FREQ=DAILY", "2024-01-01", "2024-01-10", "2024-01-12", 10,
+			[]string{"2024-01-10", "2024-01-11", "2024-01-12"}},
+		// Дальше maxPeriods периодов от начала серии перебор не идёт
+		{"beyond scan cap", "FREQ=DAILY", "2024-01-01", "2400-01-01", "2400-12-31", 10,
+			[]string{}},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := dates(rule.Between(day(tt.start), day(tt.from), day(tt.to), tt.limit))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Экземпляры серии, которые по очереди порождает Next, и COUNT каждого из них
+func spawn(rule Rule, start time.Time) ([]string, []int) {
+	starts, counts := []string{}, []int{}
+	for i := 0; i < 10; i++ {
+		starts = append(starts, start.Format("2006-01-02"))
+		counts = append(counts, rule.Count)
+		next, nextRule, ok := rule.Next(start)
+		if !ok {
+			break
+		}
+		start, rule = next, nextRule
+	}
+	return starts, counts
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		start  string
+		want   []string
+		counts []int
+	}{
+		{"count runs down", "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3", "2024-01-03",
+			[]string{"2024-01-03", "2024-01-05", "2024-01-08"}, []int{3, 2, 1}},
+		{"single instance", "FREQ=DAILY;COUNT=1", "2024-01-01",
+			[]string{"2024-01-01"}, []int{1}},
+		{"until", "FREQ=DAILY;UNTIL=20240102", "2024-01-01",
+			[]string{"2024-01-01", "2024-01-02"}, []int{0, 0}},
+		{"end of month", "FREQ=MONTHLY;COUNT=3", "2024-01-31",
+			[]string{"2024-01-31", "2024-03-31", "2024-05-31"}, []int{3, 2, 1}},
+		{"first monday", "FREQ=MONTHLY;BYDAY=1MO;COUNT=2", "2024-01-01",
+			[]string{"2024-01-01", "2024-02-05"}, []int{2, 1}},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, counts := spawn(rule, day(tt.start))
+		if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(counts, tt.counts) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, got, counts, tt.want, tt.counts)
+		}
+	}
+}
+
+func TestNextKeepsTime(t *testing.T) {
+	rule, err := Parse("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 30, 23, 30, 0, 0, time.FixedZone("MSK", 3*3600))
+	next, nextRule, ok := rule.Next(start)
+	want := time.Date(2024, 3, 31, 23, 30, 0, 0, start.Location())
+	if !ok || !next.Equal(want) || nextRule.String() != rule.String() {
+		t.Errorf("Next(%v) = %v, %q, %v, want %v, %q", start, next, nextRule, ok, want, rule)
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Сохраняет описание вложения. ID задаёт вызывающий: под ним уже лежит содержимое
+// в хранилище файлов. Задача должна существовать и не быть в корзине
+func (stor *Storage) AddAttachment(att models.Attachment) (models.Attachment, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.db[att.TaskID]; !exists {
+		return models.Attachment{}, models.ErrTaskNotFound
+	}
+	att.CreatedAt = time.Now().UTC()
+	stor.attachments[att.ID] = att
+	stor.log.Debug().Any("attachment", att).Msg("Check attachment after add")
+	return att, nil
+}
+
+// Вложения задачи в порядке загрузки
+func (stor *Storage) GetAttachments(taskID string) ([]models.Attachment, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	if _, exists := stor.db[taskID]; !exists {
+		return nil, models.ErrTaskNotFound
+	}
+	atts := []models.Attachment{}
+	for _, att := range stor.attachments {
+		if att.TaskID == taskID {
+			atts = append(atts, att)
+		}
+	}
+	sort.Slice(atts, func(i, j int) bool {
+		if !atts[i].CreatedAt.Equal(atts[j].CreatedAt) {
+			return atts[i].CreatedAt.Before(atts[j].CreatedAt)
+		}
+		return atts[i].ID < atts[j].ID
+	})
+	return atts, nil
+}
+
+// Вложение задачи taskID. Вложения задач в корзине недоступны
+func (stor *Storage) GetAttachment(taskID, id string) (models.Attachment, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	att, exists := stor.attachments[id]
+	if _, alive := stor.db[taskID]; !exists || !alive || att.TaskID != taskID {
+		return models.Attachment{}, models.ErrAttachmentNotFound
+	}
+	return att, nil
+}
+
+// Удаляет описание вложения и возвращает его, чтобы можно было удалить содержимое
+func (stor *Storage) DeleteAttachment(taskID, id string) (models.Attachment, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	att, exists := stor.attachments[id]
+	if _, alive := stor.db[taskID]; !exists || !alive || att.TaskID != taskID {
+		return models.Attachment{}, models.ErrAttachmentNotFound
+	}
+	delete(stor.attachments, id)
+	return att, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Задачи вне корзины со сроком выполнения, где пользователь userID -
+// исполнитель или автор, по сроку
+func (stor *Storage) GetCalendarTasks(userID string) ([]models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	tasks := stor.calendarTasks(userID)
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].DueAt.Equal(*tasks[j].DueAt) {
+			return tasks[i].DueAt.Before(*tasks[j].DueAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+// Отпечаток набора задач календаря пользователя: меняется при добавлении,
+// изменении и удалении любой из них. Дешевле, чем читать и сравнивать сами задачи
+func (stor *Storage) CalendarVersion(userID string) (string, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	tasks := stor.calendarTasks(userID)
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	h := sha256.New()
+	for _, task := range tasks {
+		h.Write([]byte(task.ID + ":" + strconv.FormatInt(task.Version, 10) + ","))
+	}
+	return strconv.Itoa(len(tasks)) + "-" + hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+func (stor *Storage) calendarTasks(userID string) []models.Task {
+	tasks := []models.Task{}
+	for _, task := range stor.db {
+		if task.DueAt != nil && (isUser(task.AssigneeID, userID) || isUser(task.ReporterID, userID)) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func isUser(id *string, userID string) bool {
+	return id != nil && *id == userID
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+// Комментарии к задачам в памяти
+type CommentStorage struct {
+	mu       sync.RWMutex
+	comments map[string]models.Comment
+	log      *zerolog.Logger
+}
+
+func NewComments(zlog *zerolog.Logger) *CommentStorage {
+	return &CommentStorage{
+		comments: make(map[string]models.Comment),
+		log:      zlog,
+	}
+}
+
+// Добавляет комментарий. Ответ должен ссылаться на комментарий той же задачи
+func (stor *CommentStorage) AddComment(comment models.Comment) (models.Comment, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if comment.ParentID != nil {
+		parent, exists := stor.comments[*comment.ParentID]
+		if !exists || parent.TaskID != comment.TaskID {
+			return models.Comment{}, models.ErrInvalidReply
+		}
+	}
+	comment.ID = uuid.New().String()
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = nil
+	stor.comments[comment.ID] = comment
+	stor.log.Debug().Any("comment", comment).Msg("Check comment after add")
+	return comment, nil
+}
+
+// Комментарии задачи в порядке написания
+func (stor *CommentStorage) GetComments(taskID string) ([]models.Comment, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	comments := []models.Comment{}
+	for _, comment := range stor.comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+	return comments, nil
+}
+
+func (stor *CommentStorage) GetCommentByID(id string) (models.Comment, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	comment, exists := stor.comments[id]
+	if !exists {
+		return models.Comment{}, models.ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// Меняет текст комментария
+func (stor *CommentStorage) UpdateComment(id, body string) (models.Comment, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	comment, exists := stor.comments[id]
+	if !exists {
+		return models.Comment{}, models.ErrCommentNotFound
+	}
+	now := time.Now().UTC()
+	comment.Body = body
+	comment.UpdatedAt = &now
+	stor.comments[id] = comment
+	return comment, nil
+}
+
+// Удаляет комментарий вместе со всеми ответами на него
+func (stor *CommentStorage) DeleteComment(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.comments[id]; !exists {
+		return models.ErrCommentNotFound
+	}
+	thread := []string{id}
+	for i := 0; i < len(thread); i++ {
+		for replyID, reply := range stor.comments {
+			if reply.ParentID != nil && *reply.ParentID == thread[i] {
+				thread = append(thread, replyID)
+			}
+		}
+	}
+	for _, commentID := range thread {
+		delete(stor.comments, commentID)
+	}
+	return nil
+}
+
+// Удаляет комментарии окончательно удалённых задач. Вызывается после очистки корзины
+func (stor *CommentStorage) PurgeTasks(_ context.Context, purged models.Purged) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	tasks := make(map[string]bool, len(purged.TaskIDs))
+	for _, id := range purged.TaskIDs {
+		tasks[id] = true
+	}
+	for id, comment := range stor.comments {
+		if tasks[comment.TaskID] {
+			delete(stor.comments, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Добавляет зависимость taskID от dependsOnID, если она не образует цикл
+func (stor *Storage) AddDependency(taskID, dependsOnID string) error {
+	// Зависимость от самой себя - цикл, как и в DBstorage
+	if taskID == dependsOnID {
+		return models.ErrDependencyCycle
+	}
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.db[taskID]; !exists {
+		return models.ErrTaskNotFound
+	}
+	if _, exists := stor.db[dependsOnID]; !exists {
+		return models.ErrTaskNotFound
+	}
+	// Цикл появится, если dependsOnID уже (транзитивно) зависит от taskID
+	if stor.reachable(dependsOnID, taskID) {
+		return models.ErrDependencyCycle
+	}
+	if stor.deps[taskID] == nil {
+		stor.deps[taskID] = make(map[string]bool)
+	}
+	stor.deps[taskID][dependsOnID] = true
+	return nil
+}
+
+func (stor *Storage) RemoveDependency(taskID, dependsOnID string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if !stor.deps[taskID][dependsOnID] {
+		return models.ErrTaskNotFound
+	}
+	delete(stor.deps[taskID], dependsOnID)
+	return nil
+}
+
+// Задачи, которые блокируют taskID
+func (stor *Storage) GetDependencies(taskID string) ([]models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	if _, exists := stor.db[taskID]; !exists {
+		return nil, models.ErrTaskNotFound
+	}
+	tasks := []models.Task{}
+	for id := range stor.deps[taskID] {
+		// Блокирующие задачи из корзины не показываем
+		if task, exists := stor.db[id]; exists {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return lessTask(tasks[i], tasks[j], "created_at", false)
+	})
+	return tasks, nil
+}
+
+// Открытые задачи, у которых все блокирующие задачи выполнены,
+// в топологическом порядке графа зависимостей
+func (stor *Storage) GetActionableTasks() ([]models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	levels := make(map[string]int)
+	tasks := []models.Task{}
+	for id, task := range stor.db {
+		if task.Status.Closed() || stor.blocked(id) {
+			continue
+		}
+		stor.level(id, levels)
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if levels[tasks[i].ID] != levels[tasks[j].ID] {
+			return levels[tasks[i].ID] < levels[tasks[j].ID]
+		}
+		return lessTask(tasks[i], tasks[j], "created_at", false)
+	})
+	return tasks, nil
+}
+
+func (stor *Storage) blocked(id string) bool {
+	for dep := range stor.deps[id] {
+		// Задача из корзины больше не блокирует
+		if task, exists := stor.db[dep]; exists && task.Status != models.StatusDone {
+			return true
+		}
+	}
+	return false
+}
+
+// Уровень задачи в графе: длина самой длинной цепочки блокирующих задач.
+// Граф ацикличен, поэтому рекурсия конечна
+func (stor *Storage) level(id string, levels map[string]int) int {
+	if l, ok := levels[id]; ok {
+		return l
+	}
+	l := 0
+	for dep := range stor.deps[id] {
+		l = max(l, stor.level(dep, levels)+1)
+	}
+	levels[id] = l
+	return l
+}
+
+// Есть ли путь from -> ... -> to по рёбрам зависимостей
+func (stor *Storage) reachable(from, to string) bool {
+	visited := make(map[string]bool)
+	stack := []string{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		for dep := range stor.deps[id] {
+			stack = append(stack, dep)
+		}
+	}
+	return false
+}
+
+// Удаляет все рёбра, связанные с задачей. Вызывается под stor.mu
+func (stor *Storage) removeDependencies(id string) {
+	delete(stor.deps, id)
+	for _, deps := range stor.deps {
+		delete(deps, id)
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+)
+
+// Вычисляет выражение фильтра для задачи. Вызывается под stor.mu
+func (stor *Storage) evalFilter(task models.Task, expr filter.Expr) bool {
+	switch e := expr.(type) {
+	case *filter.And:
+		return stor.evalFilter(task, e.Left) && stor.evalFilter(task, e.Right)
+	case *filter.Or:
+		return stor.evalFilter(task, e.Left) || stor.evalFilter(task, e.Right)
+	case *filter.Not:
+		return !stor.evalFilter(task, e.Expr)
+	case *filter.Cond:
+		return stor.evalCond(task, e)
+	}
+	return false
+}
+
+func (stor *Storage) evalCond(task models.Task, cond *filter.Cond) bool {
+	switch cond.Field {
+	case "title":
+		return matchText(task.Title, cond)
+	case "description":
+		return matchText(task.Description, cond)
+	case "status":
+		switch cond.Value {
+		case filter.StatusOpen:
+			return !task.Status.Closed()
+		case filter.StatusClosed:
+			return task.Status.Closed()
+		}
+		return string(task.Status) == cond.Value
+	case "label":
+		return stor.matchLabels(task.ID, []string{cond.Value}, false)
+	case "assignee":
+		return matchRef(task.AssigneeID, cond)
+	case "reporter":
+		return matchRef(task.ReporterID, cond)
+	case "project":
+		return matchRef(task.ProjectID, cond)
+	case "due":
+		return matchTime(task.DueAt, cond)
+	case "remind":
+		return matchTime(task.RemindAt, cond)
+	case "created":
+		return matchTime(&task.CreatedAt, cond)
+	}
+	return false
+}
+
+// Без учёта регистра: ":" - совпадение, "~" - подстрока
+func matchText(s string, cond *filter.Cond) bool {
+	if cond.Op == filter.OpContains {
+		return containsFold(s, cond.Value)
+	}
+	return strings.EqualFold(s, cond.Value)
+}
+
+func matchRef(id *string, cond *filter.Cond) bool {
+	if cond.None {
+		return id == nil
+	}
+	return id != nil && *id == cond.Value
+}
+
+// Сравнение с интервалом [From, To) значения условия. Пустое время не подходит
+// ни под одно сравнение, только под none
+func matchTime(t *time.Time, cond *filter.Cond) bool {
+	if cond.None || t == nil {
+		return cond.None && t == nil
+	}
+	switch cond.Op {
+	case filter.OpEqual:
+		return !t.Before(cond.From) && t.Before(cond.To)
+	case filter.OpLess:
+		return t.Before(cond.From)
+	case filter.OpLessEqual:
+		return t.Before(cond.To)
+	case filter.OpGreater:
+		return !t.Before(cond.To)
+	case filter.OpGreaterEqual:
+		return !t.Before(cond.From)
+	}
+	return false
+}
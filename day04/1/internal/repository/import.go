@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Добавляет задачи пакетом: либо все, либо ни одной. Ошибка в задаче
+// возвращается как *models.RowError с её номером в пакете
+func (stor *Storage) ImportTasks(tasks []models.Task, actor string) ([]string, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	// Не пройти при добавлении могут только родитель и проект, поэтому
+	// проверяем всех заранее и дальше добавляем без откатов
+	for i, task := range tasks {
+		err := stor.checkParent("", task.ParentID)
+		if err == nil {
+			err = stor.checkProject(task.ProjectID)
+		}
+		if err != nil {
+			return nil, &models.RowError{Row: i, Err: err}
+		}
+	}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		id, err := stor.addTask(task, actor)
+		if err != nil {
+			return nil, &models.RowError{Row: i, Err: err}
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// Передаёт в fn задачи вне корзины, подходящие под фильтры query, в порядке создания.
+// Пагинация и сортировка из query не используются
+func (stor *Storage) ExportTasks(ctx context.Context, query models.TaskQuery, fn func(models.Task) error) error {
+	// Снимок под локом, а запись - без него: медленный клиент не должен держать хранилище
+	stor.mu.RLock()
+	tasks := []models.Task{}
+	for _, task := range stor.db {
+		if stor.matchTask(task, query) {
+			tasks = append(tasks, task)
+		}
+	}
+	stor.mu.RUnlock()
+	sort.Slice(tasks, func(i, j int) bool {
+		return lessTask(tasks[i], tasks[j], "created_at", false)
+	})
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+func (stor *Storage) GetLabels() ([]models.Label, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	labels := []models.Label{}
+	for _, label := range stor.labels {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels, nil
+}
+
+func (stor *Storage) AddLabel(label models.Label) (string, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if stor.labelByName(label.Name) != nil {
+		return "", models.ErrLabelExists
+	}
+	label.ID = uuid.New().String()
+	stor.labels[label.ID] = label
+	return label.ID, nil
+}
+
+func (stor *Storage) GetLabelByID(id string) (models.Label, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	label, exists := stor.labels[id]
+	if !exists {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+	return label, nil
+}
+
+func (stor *Storage) UpdateLabel(id string, label models.Label) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.labels[id]; !exists {
+		return models.ErrLabelNotFound
+	}
+	if other := stor.labelByName(label.Name); other != nil && other.ID != id {
+		return models.ErrLabelExists
+	}
+	label.ID = id
+	stor.labels[id] = label
+	return nil
+}
+
+// Удаляет метку из каталога и снимает её со всех задач
+func (stor *Storage) DeleteLabel(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.labels[id]; !exists {
+		return models.ErrLabelNotFound
+	}
+	delete(stor.labels, id)
+	for _, labels := range stor.taskLabels {
+		delete(labels, id)
+	}
+	return nil
+}
+
+func (stor *Storage) AttachLabel(taskID, labelID string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.db[taskID]; !exists {
+		return models.ErrTaskNotFound
+	}
+	if _, exists := stor.labels[labelID]; !exists {
+		return models.ErrLabelNotFound
+	}
+	if stor.taskLabels[taskID] == nil {
+		stor.taskLabels[taskID] = make(map[string]bool)
+	}
+	stor.taskLabels[taskID][labelID] = true
+	return nil
+}
+
+func (stor *Storage) DetachLabel(taskID, labelID string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.db[taskID]; !exists {
+		return models.ErrTaskNotFound
+	}
+	if !stor.taskLabels[taskID][labelID] {
+		return models.ErrLabelNotFound
+	}
+	delete(stor.taskLabels[taskID], labelID)
+	return nil
+}
+
+func (stor *Storage) GetTaskLabels(taskID string) ([]models.Label, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	if _, exists := stor.db[taskID]; !exists {
+		return nil, models.ErrTaskNotFound
+	}
+	labels := []models.Label{}
+	for id := range stor.taskLabels[taskID] {
+		labels = append(labels, stor.labels[id])
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels, nil
+}
+
+// Вызывается под stor.mu
+func (stor *Storage) labelByName(name string) *models.Label {
+	for _, label := range stor.labels {
+		if label.Name == name {
+			return &label
+		}
+	}
+	return nil
+}
+
+// Есть ли на задаче любая из меток names (или все, если all). Вызывается под stor.mu
+func (stor *Storage) matchLabels(taskID string, names []string, all bool) bool {
+	has := make(map[string]bool)
+	for id := range stor.taskLabels[taskID] {
+		has[stor.labels[id].Name] = true
+	}
+	for _, name := range names {
+		if has[name] && !all {
+			return true
+		}
+		if !has[name] && all {
+			return false
+		}
+	}
+	return all
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/rank"
+)
+
+// Проекты в порядке создания
+func (stor *Storage) GetProjects() ([]models.Project, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	projects := []models.Project{}
+	for _, project := range stor.projects {
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if !projects[i].CreatedAt.Equal(projects[j].CreatedAt) {
+			return projects[i].CreatedAt.Before(projects[j].CreatedAt)
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
+
+func (stor *Storage) AddProject(project models.Project) (models.Project, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	project.ID = uuid.New().String()
+	project.CreatedAt = time.Now().UTC()
+	stor.projects[project.ID] = project
+	return project, nil
+}
+
+func (stor *Storage) GetProject(id string) (models.Project, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	project, exists := stor.projects[id]
+	if !exists {
+		return models.Project{}, models.ErrProjectNotFound
+	}
+	return project, nil
+}
+
+func (stor *Storage) UpdateProject(id string, project models.Project) (models.Project, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.projects[id]
+	if !exists {
+		return models.Project{}, models.ErrProjectNotFound
+	}
+	project.ID = id
+	project.CreatedAt = old.CreatedAt
+	stor.projects[id] = project
+	return project, nil
+}
+
+// Удаляет проект с его досками. Задачи остаются, но выходят из проекта
+func (stor *Storage) DeleteProject(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.projects[id]; !exists {
+		return models.ErrProjectNotFound
+	}
+	delete(stor.projects, id)
+	for boardID, board := range stor.boards {
+		if board.ProjectID == id {
+			delete(stor.boards, boardID)
+		}
+	}
+	for _, tasks := range []map[string]models.Task{stor.db, stor.trash} {
+		for taskID, task := range tasks {
+			if task.ProjectID != nil && *task.ProjectID == id {
+				task.ProjectID = nil
+				task.Rank = ""
+				tasks[taskID] = task
+			}
+		}
+	}
+	return nil
+}
+
+// Доски проекта в порядке создания
+func (stor *Storage) GetBoards(projectID string) ([]models.Board, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	if _, exists := stor.projects[projectID]; !exists {
+		return nil, models.ErrProjectNotFound
+	}
+	boards := []models.Board{}
+	for _, board := range stor.boards {
+		if board.ProjectID == projectID {
+			boards = append(boards, board)
+		}
+	}
+	sort.Slice(boards, func(i, j int) bool {
+		if !boards[i].CreatedAt.Equal(boards[j].CreatedAt) {
+			return boards[i].CreatedAt.Before(boards[j].CreatedAt)
+		}
+		return boards[i].ID < boards[j].ID
+	})
+	return boards, nil
+}
+
+func (stor *Storage) AddBoard(board models.Board) (models.Board, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.projects[board.ProjectID]; !exists {
+		return models.Board{}, models.ErrProjectNotFound
+	}
+	board.ID = uuid.New().String()
+	board.CreatedAt = time.Now().UTC()
+	stor.boards[board.ID] = board
+	return board, nil
+}
+
+func (stor *Storage) GetBoard(id string) (models.Board, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	board, exists := stor.boards[id]
+	if !exists {
+		return models.Board{}, models.ErrBoardNotFound
+	}
+	return board, nil
+}
+
+// Меняет название и колонки доски; проект доски не меняется
+func (stor *Storage) UpdateBoard(id string, board models.Board) (models.Board, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.boards[id]
+	if !exists {
+		return models.Board{}, models.ErrBoardNotFound
+	}
+	board.ID = id
+	board.ProjectID = old.ProjectID
+	board.CreatedAt = old.CreatedAt
+	stor.boards[id] = board
+	return board, nil
+}
+
+func (stor *Storage) DeleteBoard(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.boards[id]; !exists {
+		return models.ErrBoardNotFound
+	}
+	delete(stor.boards, id)
+	return nil
+}
+
+// Задачи проекта вне корзины в порядке рангов
+func (stor *Storage) GetProjectTasks(projectID string) ([]models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	if _, exists := stor.projects[projectID]; !exists {
+		return nil, models.ErrProjectNotFound
+	}
+	tasks := []models.Task{}
+	for _, task := range stor.db {
+		if task.ProjectID != nil && *task.ProjectID == projectID {
+			tasks = append(tasks, task)
+		}
+	}
+	sortByRank(tasks)
+	return tasks, nil
+}
+
+// Переставляет карточку: меняет ранг задачи и, если нужно, статус.
+// Меняется только сама задача
+func (stor *Storage) MoveTask(id string, move models.TaskMove, actor string) (models.Task, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.db[id]
+	if !exists {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	if old.Status != move.From {
+		return models.Task{}, models.ErrStatusConflict
+	}
+	if old.ProjectID == nil {
+		return models.Task{}, fmt.Errorf("%w: task has no project", models.ErrInvalidPosition)
+	}
+	var siblings []models.Task
+	for _, task := range stor.db {
+		if task.ProjectID != nil && *task.ProjectID == *old.ProjectID && task.ID != id {
+			siblings = append(siblings, task)
+		}
+	}
+	sortByRank(siblings)
+	r, err := placeBetween(siblings, move.After, move.Before)
+	if err != nil {
+		return models.Task{}, err
+	}
+	task := old
+	task.Status = move.To
+	task.Rank = r
+	task.Version++
+	if err := stor.audit(actor, models.AuditUpdate, &old, task); err != nil {
+		return models.Task{}, err
+	}
+	stor.db[id] = task
+	if completes(old, task) {
+		if err := stor.spawnNext(task, actor); err != nil {
+			return models.Task{}, err
+		}
+	}
+	return task, nil
+}
+
+// Ранг между соседями after и before среди siblings (задачи проекта по порядку,
+// без перемещаемой). Если задан только один сосед, второй - следующая за ним
+// (или предыдущая перед ним) задача проекта
+func placeBetween(siblings []models.Task, after, before string) (string, error) {
+	lo, hi := -1, len(siblings)
+	for i, task := range siblings {
+		if task.ID == after {
+			lo = i
+		}
+		if task.ID == before {
+			hi = i
+		}
+	}
+	if after != "" && lo < 0 || before != "" && hi == len(siblings) {
+		return "", fmt.Errorf("%w: neighbour is not a card of this project", models.ErrInvalidPosition)
+	}
+	switch {
+	case after != "" && before == "":
+		hi = lo + 1
+	case after == "" && before != "":
+		lo = hi - 1
+	case after == "" && before == "":
+		lo = len(siblings) - 1
+	}
+	var a, b string
+	if lo >= 0 {
+		a = siblings[lo].Rank
+	}
+	if hi < len(siblings) {
+		b = siblings[hi].Rank
+	}
+	r, err := rank.Between(a, b)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", models.ErrInvalidPosition, err)
+	}
+	return r, nil
+}
+
+// Ранг для задачи, которая встаёт в конец проекта. Вызывается под stor.mu
+func (stor *Storage) endRank(projectID *string) (string, error) {
+	if projectID == nil {
+		return "", nil
+	}
+	last := ""
+	for _, task := range stor.db {
+		if task.ProjectID != nil && *task.ProjectID == *projectID && task.Rank > last {
+			last = task.Rank
+		}
+	}
+	return rank.After(last)
+}
+
+// Проект задачи должен существовать. Вызывается под stor.mu
+func (stor *Storage) checkProject(projectID *string) error {
+	if projectID == nil {
+		return nil
+	}
+	if _, exists := stor.projects[*projectID]; !exists {
+		return fmt.Errorf("%w: project not found", models.ErrInvalidProject)
+	}
+	return nil
+}
+
+// Порядок карточек: по рангу, при равенстве - по ID (как ORDER BY rank, id)
+func sortByRank(tasks []models.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Rank != tasks[j].Rank {
+			return tasks[i].Rank < tasks[j].Rank
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Поля, по которым разрешена сортировка списка задач
+var sortFields = map[string]bool{
+	"title":      true,
+	"created_at": true,
+}
+
+// Разбирает параметр сортировки вида "title" или "-created_at"
+func parseSort(sort string) (string, bool, error) {
+	if sort == "" {
+		return "created_at", false, nil
+	}
+	desc := strings.HasPrefix(sort, "-")
+	field := strings.TrimPrefix(sort, "-")
+	if !sortFields[field] {
+		return "", false, fmt.Errorf("%w: unknown sort field %q", models.ErrInvalidQuery, field)
+	}
+	return field, desc, nil
+}
+
+// Содержимое курсора: сортировка, на которой он выдан, и ключ последней задачи страницы
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func encodeCursor(task models.Task, field string, desc bool) string {
+	c := cursor{Sort: field, ID: task.ID}
+	if desc {
+		c.Sort = "-" + field
+	}
+	switch field {
+	case "title":
+		c.Value = task.Title
+	case "created_at":
+		c.Value = task.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Восстанавливает из курсора "опорную" задачу, после которой начинается страница
+func decodeCursor(s, field string, desc bool) (models.Task, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.Task{}, fmt.Errorf("%w: malformed cursor", models.ErrInvalidQuery)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return models.Task{}, fmt.Errorf("%w: malformed cursor", models.ErrInvalidQuery)
+	}
+	sort := field
+	if desc {
+		sort = "-" + field
+	}
+	if c.Sort != sort {
+		return models.Task{}, fmt.Errorf("%w: cursor was issued for sort %q", models.ErrInvalidQuery, c.Sort)
+	}
+	pivot := models.Task{ID: c.ID}
+	switch field {
+	case "title":
+		pivot.Title = c.Value
+	case "created_at":
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return models.Task{}, fmt.Errorf("%w: malformed cursor", models.ErrInvalidQuery)
+		}
+		pivot.CreatedAt = t
+	}
+	return pivot, nil
+}
+
+// Сборщик WHERE с нумерованными параметрами для pgx
+type sqlBuilder struct {
+	conds []string
+	args  []any
+}
+
+// Добавляет параметр и возвращает его плейсхолдер ($1, $2, ...)
+func (b *sqlBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *sqlBuilder) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+func (b *sqlBuilder) clause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conds, " AND ")
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/recurrence"
+)
+
+// Изменение переводит задачу в done: пора создавать следующий экземпляр серии
+func completes(old, task models.Task) bool {
+	return old.Status != models.StatusDone && task.Status == models.StatusDone && task.Recurrence != ""
+}
+
+// ID новой серии для задачи с правилом повторения, nil для обычной задачи
+func newSeries(rule string) *string {
+	if rule == "" {
+		return nil
+	}
+	id := uuid.New().String()
+	return &id
+}
+
+// Серия задачи после изменения: та же, что была, или новая, если у задачи
+// впервые появилось правило повторения
+func keepSeries(old, task models.Task) *string {
+	if old.SeriesID != nil {
+		return old.SeriesID
+	}
+	return newSeries(task.Recurrence)
+}
+
+// Следующий экземпляр серии после выполненной задачи task: те же заголовок, описание,
+// родитель, исполнитель, автор и проект, срок - следующее повторение, напоминание сдвигается вместе со сроком.
+// false, если повторений больше нет
+func nextInstance(task models.Task) (models.Task, bool, error) {
+	if task.Recurrence == "" || task.DueAt == nil {
+		return models.Task{}, false, nil
+	}
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return models.Task{}, false, err
+	}
+	due, rest, ok := rule.Next(*task.DueAt)
+	if !ok {
+		return models.Task{}, false, nil
+	}
+	series := task.SeriesID
+	if series == nil {
+		series = newSeries(task.Recurrence)
+	}
+	next := models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      models.StatusTodo,
+		DueAt:       &due,
+		ParentID:    task.ParentID,
+		AssigneeID:  task.AssigneeID,
+		ReporterID:  task.ReporterID,
+		ProjectID:   task.ProjectID,
+		Recurrence:  rest.String(),
+		SeriesID:    series,
+	}
+	if task.RemindAt != nil {
+		remind := due.Add(task.RemindAt.Sub(*task.DueAt))
+		next.RemindAt = &remind
+	}
+	return next, true, nil
+}
+
+// Создаёт следующий экземпляр серии после выполнения task, если его ещё нет.
+// Метки переходят к новому экземпляру. Вызывается под stor.mu
+func (stor *Storage) spawnNext(task models.Task, actor string) error {
+	next, ok, err := nextInstance(task)
+	if err != nil || !ok {
+		return err
+	}
+	for _, tasks := range []map[string]models.Task{stor.db, stor.trash} {
+		for _, t := range tasks {
+			if t.SeriesID != nil && *t.SeriesID == *next.SeriesID && equalTime(t.DueAt, next.DueAt) {
+				return nil
+			}
+		}
+	}
+	if next.Rank, err = stor.endRank(next.ProjectID); err != nil {
+		return err
+	}
+	next.ID = uuid.New().String()
+	next.CreatedAt = time.Now().UTC()
+	next.Version = 1
+	if err := stor.audit(actor, models.AuditCreate, nil, next); err != nil {
+		return err
+	}
+	stor.db[next.ID] = next
+	stor.index.add(next)
+	for labelID := range stor.taskLabels[task.ID] {
+		if stor.taskLabels[next.ID] == nil {
+			stor.taskLabels[next.ID] = make(map[string]bool)
+		}
+		stor.taskLabels[next.ID][labelID] = true
+	}
+	return nil
+}
@@ -1,7 +1,11 @@
 package repository
 
 import (
-	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
@@ -9,63 +13,402 @@ import (
 )
 
 type Storage struct {
+	mu sync.RWMutex
 	db map[string]models.Task
-	log *zerolog.Logger
+	// Корзина: удалённые задачи до окончательной очистки
+	trash map[string]models.Task
+	// Отправленные напоминания: ID задачи -> вид напоминания
+	reminded map[string]map[models.ReminderKind]bool
+	// Зависимости: ID задачи -> множество ID задач, которые её блокируют
+	deps map[string]map[string]bool
+	// Каталог меток и связи: ID задачи -> множество ID меток
+	labels     map[string]models.Label
+	taskLabels map[string]map[string]bool
+	// Описания вложений задач
+	attachments map[string]models.Attachment
+	// Проекты и их доски
+	projects map[string]models.Project
+	boards   map[string]models.Board
+	// Журнал изменений задач (только дописывается)
+	history []models.AuditRecord
+	// Полнотекстовый индекс по заголовку и описанию
+	index *invertedIndex
+	log   *zerolog.Logger
 }
 
-
 func New(zlog *zerolog.Logger) *Storage {
-	db :=  make(map[string]models.Task)
+	db := make(map[string]models.Task)
 	return &Storage{
-		db: db,
-		log: zlog,
+		db:          db,
+		trash:       make(map[string]models.Task),
+		reminded:    make(map[string]map[models.ReminderKind]bool),
+		deps:        make(map[string]map[string]bool),
+		labels:      make(map[string]models.Label),
+		taskLabels:  make(map[string]map[string]bool),
+		attachments: make(map[string]models.Attachment),
+		projects:    make(map[string]models.Project),
+		boards:      make(map[string]models.Board),
+		index:       newInvertedIndex(),
+		log:         zlog,
+	}
+}
+
+func (stor *Storage) AddTask(data models.Task, actor string) (string, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if err := stor.checkParent("", data.ParentID); err != nil {
+		return "", err
+	}
+	if err := stor.checkProject(data.ProjectID); err != nil {
+		return "", err
 	}
+	return stor.addTask(data, actor)
 }
 
-func (stor *Storage) AddTask(data models.Task)(string, error) {
+// Добавляет задачу, родитель и проект которой уже проверены. Вызывается под stor.mu.
+// Общий шаг AddTask и пакетных добавлений (дерево, импорт)
+func (stor *Storage) addTask(data models.Task, actor string) (string, error) {
+	rank, err := stor.endRank(data.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	data.Rank = rank
 	taskID := uuid.New().String()
 	data.ID = taskID
+	data.CreatedAt = time.Now().UTC()
+	data.Version = 1
+	data.SeriesID = newSeries(data.Recurrence)
+	data.DeletedAt = nil
+	if data.Status == "" {
+		data.Status = models.StatusTodo
+	}
+	if err := stor.audit(actor, models.AuditCreate, nil, data); err != nil {
+		return "", err
+	}
 	stor.db[taskID] = data
+	stor.index.add(data)
 	stor.log.Debug().Any("db", stor.db).Msg("Check db after add task")
 	return taskID, nil
 }
 
-func (stor *Storage) GetAllTasks()([]models.Task, error) {
-	var tasks []models.Task
+func (stor *Storage) GetAllTasks(query models.TaskQuery) (models.TaskPage, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	field, desc, err := parseSort(query.Sort)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
+	var pivot *models.Task
+	if query.Cursor != "" {
+		p, err := decodeCursor(query.Cursor, field, desc)
+		if err != nil {
+			return models.TaskPage{}, err
+		}
+		pivot = &p
+	}
+
+	tasks := []models.Task{}
 	for _, task := range stor.db {
-		tasks = append(tasks, task)
+		if stor.matchTask(task, query) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return lessTask(tasks[i], tasks[j], field, desc)
+	})
+
+	start := min(query.Offset, len(tasks))
+	if pivot != nil {
+		start = sort.Search(len(tasks), func(i int) bool {
+			return lessTask(*pivot, tasks[i], field, desc)
+		})
+	}
+	end := len(tasks)
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+
+	page := models.TaskPage{Tasks: tasks[start:end], Total: len(tasks)}
+	if end < len(tasks) && end > start {
+		page.NextCursor = encodeCursor(tasks[end-1], field, desc)
 	}
 	stor.log.Debug().Any("db", stor.db).Msg("Check db after get all tasks")
-	return tasks, nil
+	return page, nil
 }
 
 func (stor *Storage) GetTaskByID(id string) (models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
 	task, exists := stor.db[id]
 	if !exists {
-		return models.Task{}, errors.New("task not found")
+		return models.Task{}, models.ErrTaskNotFound
 	}
 	stor.log.Debug().Any("db", stor.db).Msg("Check db after get task by ID")
 	return task, nil
 }
 
-func (stor *Storage) UpdateTask(id string, task models.Task) error {
-	if _, exists := stor.db[id]; !exists {
-		return errors.New("task not found")
+// Сохраняет задачу. Если task.Version не AnyVersion, она должна совпадать с текущей
+func (stor *Storage) UpdateTask(id string, task models.Task, actor string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.db[id]
+	if !exists {
+		return models.ErrTaskNotFound
+	}
+	if task.Version != models.AnyVersion && task.Version != old.Version {
+		return models.ErrVersionMismatch
+	}
+	if err := stor.checkParent(id, task.ParentID); err != nil {
+		return err
+	}
+	if err := stor.checkProject(task.ProjectID); err != nil {
+		return err
+	}
+	// Задача, перенесённая в другой проект, встаёт в конец его доски
+	task.Rank = old.Rank
+	if !equalID(old.ProjectID, task.ProjectID) {
+		rank, err := stor.endRank(task.ProjectID)
+		if err != nil {
+			return err
+		}
+		task.Rank = rank
 	}
 	task.ID = id
+	task.CreatedAt = old.CreatedAt
+	task.Version = old.Version + 1
+	task.SeriesID = keepSeries(old, task)
+	task.DeletedAt = nil
+	if err := stor.audit(actor, models.AuditUpdate, &old, task); err != nil {
+		return err
+	}
+	// Новые сроки - напоминания нужно отправить заново
+	if !equalTime(old.DueAt, task.DueAt) || !equalTime(old.RemindAt, task.RemindAt) {
+		delete(stor.reminded, id)
+	}
 	stor.db[id] = task
+	stor.index.remove(old)
+	stor.index.add(task)
+	if completes(old, task) {
+		if err := stor.spawnNext(task, actor); err != nil {
+			return err
+		}
+	}
 	stor.log.Debug().Any("db", stor.db).Msg("Check db after update task")
 	return nil
 }
 
-func (stor *Storage) DeleteTask(id string) error {
-	if _, exists := stor.db[id]; !exists {
-		return errors.New("task not found")
+// Меняет статус задачи, только если текущий статус равен from
+func (stor *Storage) UpdateTaskStatus(id string, from, to models.TaskStatus, actor string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	task, exists := stor.db[id]
+	if !exists {
+		return models.ErrTaskNotFound
+	}
+	if task.Status != from {
+		return models.ErrStatusConflict
+	}
+	old := task
+	task.Status = to
+	task.Version++
+	if err := stor.audit(actor, models.AuditUpdate, &old, task); err != nil {
+		return err
+	}
+	stor.db[id] = task
+	if completes(old, task) {
+		if err := stor.spawnNext(task, actor); err != nil {
+			return err
+		}
+	}
+	stor.log.Debug().Any("db", stor.db).Msg("Check db after update task status")
+	return nil
+}
+
+// Переносит задачу в корзину. Подзадачи отправляются в корзину вместе с ней (cascade)
+// или переходят к её родителю. Если version не AnyVersion, она должна совпадать с текущей
+func (stor *Storage) DeleteTask(id string, version int64, cascade bool, actor string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	task, exists := stor.db[id]
+	if !exists {
+		return models.ErrTaskNotFound
+	}
+	if version != models.AnyVersion && version != task.Version {
+		return models.ErrVersionMismatch
+	}
+	now := time.Now().UTC()
+	for _, child := range stor.children(id) {
+		if cascade {
+			if err := stor.trashSubtree(child.ID, now, actor); err != nil {
+				return err
+			}
+			continue
+		}
+		old := child
+		child.ParentID = task.ParentID
+		child.Version++
+		if err := stor.audit(actor, models.AuditUpdate, &old, child); err != nil {
+			return err
+		}
+		stor.db[child.ID] = child
+	}
+	if err := stor.trashTask(id, now, actor); err != nil {
+		return err
 	}
-	delete(stor.db, id)
 	stor.log.Debug().Any("db", stor.db).Msg("Check db after delete task")
 	return nil
 }
 
+// Переносит задачу в корзину. Связи задачи сохраняются до окончательной очистки.
+// Вызывается под stor.mu
+func (stor *Storage) trashTask(id string, at time.Time, actor string) error {
+	old := stor.db[id]
+	task := old
+	task.DeletedAt = &at
+	task.Version++
+	if err := stor.audit(actor, models.AuditDelete, &old, task); err != nil {
+		return err
+	}
+	stor.index.remove(old)
+	delete(stor.db, id)
+	stor.trash[id] = task
+	return nil
+}
+
+// Удаляет задачу из корзины окончательно вместе со всем, что с ней связано.
+// Возвращает удалённые вложения. Вызывается под stor.mu
+func (stor *Storage) purgeTask(id string) []models.Attachment {
+	var atts []models.Attachment
+	delete(stor.trash, id)
+	delete(stor.reminded, id)
+	stor.removeDependencies(id)
+	delete(stor.taskLabels, id)
+	for attID, att := range stor.attachments {
+		if att.TaskID == id {
+			atts = append(atts, att)
+			delete(stor.attachments, attID)
+		}
+	}
+	return atts
+}
+
+// Выбирает напоминания, которые пора отправить, и сразу помечает их отправленными
+func (stor *Storage) ClaimReminders(now time.Time, lead time.Duration, limit int) ([]models.Reminder, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	var reminders []models.Reminder
+	for id, task := range stor.db {
+		if task.Status.Closed() {
+			continue
+		}
+		for _, kind := range dueReminders(task, now, lead) {
+			if len(reminders) >= limit {
+				return reminders, nil
+			}
+			if stor.reminded[id][kind] {
+				continue
+			}
+			if stor.reminded[id] == nil {
+				stor.reminded[id] = make(map[models.ReminderKind]bool)
+			}
+			stor.reminded[id][kind] = true
+			reminders = append(reminders, models.Reminder{Kind: kind, Task: task})
+		}
+	}
+	return reminders, nil
+}
+
+// Снимает отметку об отправке, чтобы напоминание ушло при следующем проходе
+func (stor *Storage) ReleaseReminder(reminder models.Reminder) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	delete(stor.reminded[reminder.Task.ID], reminder.Kind)
+	return nil
+}
+
+// Какие напоминания по задаче уже наступили к моменту now. По просроченной
+// задаче уходит только напоминание о просрочке: "скоро срок" уже не к месту
+func dueReminders(task models.Task, now time.Time, lead time.Duration) []models.ReminderKind {
+	if task.DueAt != nil && !task.DueAt.After(now) {
+		return []models.ReminderKind{models.ReminderOverdue}
+	}
+	var kinds []models.ReminderKind
+	switch {
+	case task.RemindAt != nil:
+		if !task.RemindAt.After(now) {
+			kinds = append(kinds, models.ReminderUpcoming)
+		}
+	case task.DueAt != nil:
+		if !task.DueAt.After(now.Add(lead)) {
+			kinds = append(kinds, models.ReminderUpcoming)
+		}
+	}
+	return kinds
+}
 
+func equalID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
 
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Проверяет задачу на соответствие фильтрам запроса. Вызывается под stor.mu
+func (stor *Storage) matchTask(task models.Task, query models.TaskQuery) bool {
+	if query.Title != "" && !containsFold(task.Title, query.Title) {
+		return false
+	}
+	if query.Description != "" && !containsFold(task.Description, query.Description) {
+		return false
+	}
+	if len(query.Status) > 0 && !slices.Contains(query.Status, task.Status) {
+		return false
+	}
+	if len(query.Labels) > 0 && !stor.matchLabels(task.ID, query.Labels, query.LabelsMatchAll) {
+		return false
+	}
+	if query.AssigneeID != "" && (task.AssigneeID == nil || *task.AssigneeID != query.AssigneeID) {
+		return false
+	}
+	if query.ReporterID != "" && (task.ReporterID == nil || *task.ReporterID != query.ReporterID) {
+		return false
+	}
+	if query.ProjectID != "" && (task.ProjectID == nil || *task.ProjectID != query.ProjectID) {
+		return false
+	}
+	if query.Filter != nil && !stor.evalFilter(task, query.Filter) {
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Порядок задач в выдаче: по полю сортировки, при равенстве - по ID.
+// Совпадает с ORDER BY <field>, id в DBstorage; названия сравниваются
+// побайтно, там - с COLLATE "C"
+func lessTask(a, b models.Task, field string, desc bool) bool {
+	var c int
+	switch field {
+	case "title":
+		c = strings.Compare(a.Title, b.Title)
+	case "created_at":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if desc {
+		return c > 0
+	}
+	return c < 0
+}
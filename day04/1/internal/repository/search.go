@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Веса совпадений в заголовке и описании, как у ts_rank для весов A и B
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// Параметры фрагмента описания, как у ts_headline в DBstorage
+const (
+	snippetMaxWords = 35
+	snippetLead     = 5
+)
+
+// Инвертированный индекс: слово -> ID задачи -> число вхождений.
+// Разбиение на слова повторяет конфигурацию 'simple' в Postgres:
+// нижний регистр, без стемминга
+type invertedIndex struct {
+	postings map[string]map[string]termFreq
+}
+
+type termFreq struct {
+	title       int
+	description int
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{postings: make(map[string]map[string]termFreq)}
+}
+
+func (idx *invertedIndex) add(task models.Task) {
+	for _, word := range tokenize(task.Title) {
+		tf := idx.posting(word, task.ID)
+		tf.title++
+		idx.postings[word][task.ID] = tf
+	}
+	for _, word := range tokenize(task.Description) {
+		tf := idx.posting(word, task.ID)
+		tf.description++
+		idx.postings[word][task.ID] = tf
+	}
+}
+
+func (idx *invertedIndex) remove(task models.Task) {
+	for _, word := range append(tokenize(task.Title), tokenize(task.Description)...) {
+		delete(idx.postings[word], task.ID)
+		if len(idx.postings[word]) == 0 {
+			delete(idx.postings, word)
+		}
+	}
+}
+
+func (idx *invertedIndex) posting(word, id string) termFreq {
+	if idx.postings[word] == nil {
+		idx.postings[word] = make(map[string]termFreq)
+	}
+	return idx.postings[word][id]
+}
+
+// Задачи, в которых встречаются все слова запроса, с их рангом
+func (idx *invertedIndex) search(words []string) map[string]float64 {
+	ranks := make(map[string]float64)
+	for i, word := range words {
+		next := make(map[string]float64)
+		for id, tf := range idx.postings[word] {
+			if _, ok := ranks[id]; i > 0 && !ok {
+				continue
+			}
+			next[id] = ranks[id] + titleWeight*float64(tf.title) + descriptionWeight*float64(tf.description)
+		}
+		ranks = next
+	}
+	return ranks
+}
+
+func (stor *Storage) SearchTasks(q string, limit int) ([]models.SearchResult, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	words := tokenize(q)
+	results := []models.SearchResult{}
+	if len(words) == 0 {
+		return results, nil
+	}
+	terms := make(map[string]bool, len(words))
+	for _, word := range words {
+		terms[word] = true
+	}
+	for id, rank := range stor.index.search(words) {
+		task := stor.db[id]
+		results = append(results, models.SearchResult{
+			Task:               task,
+			Rank:               rank,
+			TitleSnippet:       highlight(task.Title, terms, 0),
+			DescriptionSnippet: highlight(task.Description, terms, snippetMaxWords),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return lessTask(results[i].Task, results[j].Task, "created_at", false)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Оборачивает найденные слова в <b></b>, остальной текст экранирует для HTML.
+// Если maxWords > 0, текст обрезается
+// до окна из maxWords слов, начинающегося чуть раньше первого совпадения
+func highlight(text string, terms map[string]bool, maxWords int) string {
+	type word struct{ start, end int }
+	var words []word
+	start := -1
+	for i, r := range text {
+		if isSeparator(r) {
+			if start >= 0 {
+				words = append(words, word{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, word{start, len(text)})
+	}
+
+	from, to := 0, len(words)
+	if maxWords > 0 && len(words) > maxWords {
+		for i, w := range words {
+			if terms[strings.ToLower(text[w.start:w.end])] {
+				from = max(0, i-snippetLead)
+				break
+			}
+		}
+		to = min(len(words), from+maxWords)
+	}
+	if len(words) == 0 {
+		return html.EscapeString(text)
+	}
+
+	var b strings.Builder
+	pos := words[from].start
+	if from == 0 {
+		pos = 0
+	}
+	for _, w := range words[from:to] {
+		b.WriteString(html.EscapeString(text[pos:w.start]))
+		if terms[strings.ToLower(text[w.start:w.end])] {
+			b.WriteString("<b>" + html.EscapeString(text[w.start:w.end]) + "</b>")
+		} else {
+			b.WriteString(html.EscapeString(text[w.start:w.end]))
+		}
+		pos = w.end
+	}
+	if to == len(words) {
+		b.WriteString(html.EscapeString(text[pos:]))
+	}
+	return b.String()
+}
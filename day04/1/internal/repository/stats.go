@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Ключ сводки для задач без исполнителя
+const noAssignee = "none"
+
+// Шаг статистики по дням. Сутки UTC всегда 24 часа
+const flowStep = 24 * time.Hour
+
+// Сводка по задачам вне корзины, по всем или по проекту
+func (stor *Storage) GetTaskStats(projectID string) (models.TaskStats, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	stats := newTaskStats()
+	for _, task := range stor.db {
+		if projectID != "" && (task.ProjectID == nil || *task.ProjectID != projectID) {
+			continue
+		}
+		stats.Total++
+		stats.ByStatus[task.Status]++
+		assignee := noAssignee
+		if task.AssigneeID != nil {
+			assignee = *task.AssigneeID
+		}
+		stats.ByAssignee[assignee]++
+		for id := range stor.taskLabels[task.ID] {
+			stats.ByLabel[stor.labels[id].Name]++
+		}
+	}
+	return stats, nil
+}
+
+// Состояние задачи, восстановленное по журналу
+type flowState struct {
+	// Последняя операция создания, удаления, восстановления или очистки
+	op      models.AuditOperation
+	status  string
+	project string
+}
+
+// Число задач в каждом статусе на конец каждого дня query. Задача учитывается,
+// если к концу дня она создана и не в корзине; статус и проект берутся из последних
+// их изменений в журнале. Журнал проходится один раз: каждая запись меняет состояние
+// задачи, и если от этого меняется её вклад в счётчики, изменение относится к дню
+// записи. Тот же расчёт в DBstorage.GetTaskFlow делает SQL
+func (stor *Storage) GetTaskFlow(query models.FlowQuery) ([]models.FlowPoint, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	end := query.To.Add(flowStep)
+	states := make(map[string]*flowState)
+	deltas := make(map[int]map[models.TaskStatus]int)
+	add := func(day int, status string, n int) {
+		if status == "" {
+			return
+		}
+		if deltas[day] == nil {
+			deltas[day] = make(map[models.TaskStatus]int)
+		}
+		deltas[day][models.TaskStatus(status)] += n
+	}
+	for _, record := range stor.history {
+		if !record.At.Before(end) {
+			continue
+		}
+		state := states[record.EntityID]
+		if state == nil {
+			state = &flowState{}
+			states[record.EntityID] = state
+		}
+		before := state.counted(query.ProjectID)
+		switch record.Operation {
+		case models.AuditCreate, models.AuditDelete, models.AuditRestore, models.AuditPurge:
+			state.op = record.Operation
+		}
+		if change, ok := record.Diff["status"]; ok {
+			state.status, _ = change.New.(string)
+		}
+		if change, ok := record.Diff["project_id"]; ok {
+			state.project, _ = change.New.(string)
+		}
+		if after := state.counted(query.ProjectID); after != before {
+			// Первый день, к концу которого запись уже есть; записи до From - нулевой
+			day := max(int(record.At.Sub(query.From)/flowStep), 0)
+			add(day, before, -1)
+			add(day, after, 1)
+		}
+	}
+	return accumulateFlow(query, deltas), nil
+}
+
+// Статус, в котором задача учитывается в счётчиках, или пустая строка,
+// если она не создана, в корзине или из другого проекта
+func (state *flowState) counted(projectID string) string {
+	alive := state.op == models.AuditCreate || state.op == models.AuditRestore
+	if !alive || projectID != "" && state.project != projectID {
+		return ""
+	}
+	return state.status
+}
+
+// Точки по дням query из изменений счётчиков: deltas[n][status] - на сколько
+// изменилось число задач в статусе за n-й день от query.From
+func accumulateFlow(query models.FlowQuery, deltas map[int]map[models.TaskStatus]int) []models.FlowPoint {
+	counts := make(map[models.TaskStatus]int)
+	points := []models.FlowPoint{}
+	for n, day := 0, query.From; !day.After(query.To); n, day = n+1, day.Add(flowStep) {
+		for status, delta := range deltas[n] {
+			counts[status] += delta
+		}
+		point := newFlowPoint(day)
+		for status, count := range counts {
+			point.Counts[status] = count
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+func newTaskStats() models.TaskStats {
+	stats := models.TaskStats{
+		ByStatus:   make(map[models.TaskStatus]int),
+		ByAssignee: make(map[string]int),
+		ByLabel:    make(map[string]int),
+	}
+	for _, status := range models.Statuses {
+		stats.ByStatus[status] = 0
+	}
+	return stats
+}
+
+// Точка с нулями по всем статусам, чтобы у графика не было пропусков
+func newFlowPoint(day time.Time) models.FlowPoint {
+	point := models.FlowPoint{
+		Date:   day.UTC().Format(time.DateOnly),
+		Counts: make(map[models.TaskStatus]int),
+	}
+	for _, status := range models.Statuses {
+		point.Counts[status] = 0
+	}
+	return point
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+var flowFrom = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// Хранилище с заданным журналом; ID записей - в порядке журнала
+func newFlowStorage(records []models.AuditRecord) *Storage {
+	zlog := zerolog.Nop()
+	stor := New(&zlog)
+	for i := range records {
+		records[i].ID = int64(i + 1)
+	}
+	stor.history = records
+	return stor
+}
+
+func flowRecord(task string, op models.AuditOperation, at string, fields ...string) models.AuditRecord {
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		panic(err)
+	}
+	diff := make(map[string]models.FieldChange)
+	for i := 0; i+1 < len(fields); i += 2 {
+		diff[fields[i]] = models.FieldChange{New: fields[i+1]}
+	}
+	return models.AuditRecord{EntityID: task, Operation: op, At: t, Diff: diff}
+}
+
+// Ненулевые счётчики точек: дата -> статус -> число задач
+func flowCounts(points []models.FlowPoint) map[string]map[models.TaskStatus]int {
+	counts := make(map[string]map[models.TaskStatus]int)
+	for _, point := range points {
+		counts[point.Date] = make(map[models.TaskStatus]int)
+		for status, n := range point.Counts {
+			if n != 0 {
+				counts[point.Date][status] = n
+			}
+		}
+	}
+	return counts
+}
+
+func TestTaskFlow(t *testing.T) {
+	stor := newFlowStorage([]models.AuditRecord{
+		flowRecord("a", models.AuditCreate, "2024-02-28T09:00:00Z", "status", "todo", "project_id", "p1"),
+		flowRecord("b", models.AuditCreate, "2024-03-01T12:00:00Z", "status", "todo"),
+		// Ровно на границе суток - уже следующий день
+		flowRecord("a", models.AuditUpdate, "2024-03-02T00:00:00Z", "status", "in_progress"),
+		flowRecord("b", models.AuditDelete, "2024-03-02T15:00:00Z"),
+		flowRecord("c", models.AuditCreate, "2024-03-03T08:00:00Z", "status", "todo", "project_id", "p2"),
+		flowRecord("b", models.AuditRestore, "2024-03-03T09:00:00Z"),
+		flowRecord("c", models.AuditUpdate, "2024-03-03T18:00:00Z", "project_id", "p1"),
+		flowRecord("a", models.AuditDelete, "2024-03-04T10:00:00Z"),
+		flowRecord("a", models.AuditPurge, "2024-03-05T12:00:00Z"),
+		flowRecord("b", models.AuditUpdate, "2024-03-05T23:59:59Z", "status", "done"),
+		// После конца последнего дня - не учитывается
+		flowRecord("c", models.AuditUpdate, "2024-03-06T00:00:00Z", "status", "cancelled"),
+	})
+	tests := []struct {
+		project string
+		want    map[string]map[models.TaskStatus]int
+	}{
+		{"", map[string]map[models.TaskStatus]int{
+			"2024-03-01": {"todo": 2},
+			"2024-03-02": {"in_progress": 1},
+			"2024-03-03": {"in_progress": 1, "todo": 2},
+			"2024-03-04": {"todo": 2},
+			"2024-03-05": {"todo": 1, "done": 1},
+		}},
+		{"p1", map[string]map[models.TaskStatus]int{
+			"2024-03-01": {"todo": 1},
+			"2024-03-02": {"in_progress": 1},
+			"2024-03-03": {"in_progress": 1, "todo": 1},
+			"2024-03-04": {"todo": 1},
+			"2024-03-05": {"todo": 1},
+		}},
+	}
+	for _, tt := range tests {
+		points, err := stor.GetTaskFlow(models.FlowQuery{From: flowFrom, To: flowFrom.Add(4 * flowStep), ProjectID: tt.project})
+		if err != nil {
+			t.Fatalf("GetTaskFlow(%q): %v", tt.project, err)
+		}
+		if got := flowCounts(points); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetTaskFlow(%q) = %v, want %v", tt.project, got, tt.want)
+		}
+	}
+}
+
+// Счётчики, пересчитанные для каждого дня заново по всему журналу
+func replayFlow(history []models.AuditRecord, query models.FlowQuery) []models.FlowPoint {
+	points := []models.FlowPoint{}
+	for day := query.From; !day.After(query.To); day = day.Add(flowStep) {
+		states := make(map[string]*flowState)
+		for _, record := range history {
+			if !record.At.Before(day.Add(flowStep)) {
+				continue
+			}
+			state := states[record.EntityID]
+			if state == nil {
+				state = &flowState{}
+				states[record.EntityID] = state
+			}
+			switch record.Operation {
+			case models.AuditCreate, models.AuditDelete, models.AuditRestore, models.AuditPurge:
+				state.op = record.Operation
+			}
+			if change, ok := record.Diff["status"]; ok {
+				state.status, _ = change.New.(string)
+			}
+			if change, ok := record.Diff["project_id"]; ok {
+				state.project, _ = change.New.(string)
+			}
+		}
+		point := newFlowPoint(day)
+		for _, state := range states {
+			if status := state.counted(query.ProjectID); status != "" {
+				point.Counts[models.TaskStatus(status)]++
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// Проекты в случайных журналах; пустой - без проекта и, в запросе, все задачи
+var flowProjects = []string{"", "p1", "p2"}
+
+// Случайный журнал шести задач в порядке времени. taskID задаёт ID задачи по номеру
+func randomFlowHistory(rnd *rand.Rand, taskID func(n int) string) []models.AuditRecord {
+	ops := []models.AuditOperation{models.AuditCreate, models.AuditUpdate, models.AuditUpdate,
+		models.AuditDelete, models.AuditRestore, models.AuditPurge}
+	var records []models.AuditRecord
+	for i := 0; i < 80; i++ {
+		record := models.AuditRecord{
+			EntityID:  taskID(rnd.Intn(6)),
+			Operation: ops[rnd.Intn(len(ops))],
+			// Шаг в 6 часов, чтобы часть записей попадала ровно на границы суток
+			At:   flowFrom.Add(time.Duration(rnd.Intn(40)-8) * 6 * time.Hour),
+			Diff: make(map[string]models.FieldChange),
+		}
+		if rnd.Intn(2) == 0 {
+			record.Diff["status"] = models.FieldChange{New: string(models.Statuses[rnd.Intn(len(models.Statuses))])}
+		}
+		if rnd.Intn(4) == 0 {
+			record.Diff["project_id"] = models.FieldChange{New: flowProjects[rnd.Intn(len(flowProjects))]}
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].At.Before(records[j].At) })
+	return records
+}
+
+func TestTaskFlowMatchesReplay(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for run := 0; run < 50; run++ {
+		records := randomFlowHistory(rnd, func(n int) string { return fmt.Sprintf("task-%d", n) })
+		stor := newFlowStorage(records)
+		for _, project := range flowProjects {
+			query := models.FlowQuery{From: flowFrom, To: flowFrom.Add(6 * flowStep), ProjectID: project}
+			got, err := stor.GetTaskFlow(query)
+			if err != nil {
+				t.Fatalf("GetTaskFlow: %v", err)
+			}
+			if want := replayFlow(records, query); !reflect.DeepEqual(got, want) {
+				t.Fatalf("run %d, project %q: GetTaskFlow = %v, want %v", run, project, got, want)
+			}
+		}
+	}
+}
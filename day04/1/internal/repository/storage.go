@@ -2,88 +2,471 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
 )
 
 type DBstorage struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
 }
 
-func NewDB(conn *pgx.Conn) DBstorage {
+func NewDB(conn *pgxpool.Pool) DBstorage {
 	return DBstorage{
 		conn: conn,
 	}
 }
 
-func (db *DBstorage) GetAllTasks() ([]models.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	rows, err := db.conn.Query(ctx, "SELECT id, title, description FROM tasks")
-	if err != nil {
-		return nil, err
+// Колонки задачи в порядке, который ожидает scanTask
+const taskColumns = `id, title, description, status, created_at, due_at, remind_at, parent_id, recurrence, series_id,
+	assignee_id, reporter_id, project_id, rank, version, deleted_at`
+
+// extra - дополнительные колонки, идущие в выборке после taskColumns
+func scanTask(row pgx.Row, extra ...any) (models.Task, error) {
+	var task models.Task
+	dest := []any{&task.ID, &task.Title, &task.Description, &task.Status, &task.CreatedAt, &task.DueAt, &task.RemindAt,
+		&task.ParentID, &task.Recurrence, &task.SeriesID, &task.AssigneeID, &task.ReporterID,
+		&task.ProjectID, &task.Rank, &task.Version, &task.DeletedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.Task{}, err
 	}
+	task.Title = strings.TrimSpace(task.Title)
+	task.Description = strings.TrimSpace(task.Description)
+	return task, nil
+}
+
+// Читает все строки выборки с колонками taskColumns
+func collectTasks(rows pgx.Rows) ([]models.Task, error) {
 	defer rows.Close()
-	var tasks []models.Task
+	tasks := []models.Task{}
 	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Description); err != nil {
+		task, err := scanTask(rows)
+		if err != nil {
 			return nil, err
 		}
-		task.Title = strings.TrimSpace(task.Title)
-		task.Description = strings.TrimSpace(task.Description)
 		tasks = append(tasks, task)
 	}
-	return tasks, nil
+	return tasks, rows.Err()
+}
+
+// Приводит ошибки "строки нет" к models.ErrTaskNotFound: пустой результат,
+// нарушение внешнего ключа и ID, который не является UUID
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrTaskNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "23503" || pgErr.Code == "22P02") {
+		return models.ErrTaskNotFound
+	}
+	return err
 }
 
-func (db *DBstorage) GetTasksByID(id string) (models.Task, error) {
+func (db *DBstorage) GetAllTasks(query models.TaskQuery) (models.TaskPage, error) {
+	field, desc, err := parseSort(query.Sort)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	row := db.conn.QueryRow(ctx, "SELECT title, description FROM tasks WHERE id=$1", id)
-	var task models.Task
-	if err := row.Scan(&task.Title, &task.Description); err != nil {
-		return models.Task{}, err
+
+	b := &sqlBuilder{}
+	b.where("deleted_at IS NULL")
+	applyTaskFilters(b, query)
+	var page models.TaskPage
+	if err := db.conn.QueryRow(ctx, "SELECT count(*) FROM tasks"+b.clause(), b.args...).Scan(&page.Total); err != nil {
+		return models.TaskPage{}, err
+	}
+
+	// Названия сравниваются побайтно, как strings.Compare в Storage: в правиле
+	// сортировки базы данных порядок (а с ним и страницы) был бы другим
+	column := field
+	if field == "title" {
+		column = `title COLLATE "C"`
+	}
+	dir, op := "ASC", ">"
+	if desc {
+		dir, op = "DESC", "<"
+	}
+	if query.Cursor != "" {
+		pivot, err := decodeCursor(query.Cursor, field, desc)
+		if err != nil {
+			return models.TaskPage{}, err
+		}
+		var value any = pivot.Title
+		if field == "created_at" {
+			value = pivot.CreatedAt
+		}
+		b.where(fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, b.arg(value), b.arg(pivot.ID)))
+	}
+	sql := "SELECT " + taskColumns + " FROM tasks" + b.clause() +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", column, dir, dir)
+	if query.Limit > 0 {
+		// Берём на одну строку больше, чтобы понять, есть ли следующая страница
+		sql += " LIMIT " + b.arg(query.Limit+1)
+	}
+	if query.Cursor == "" && query.Offset > 0 {
+		sql += " OFFSET " + b.arg(query.Offset)
+	}
+
+	rows, err := db.conn.Query(ctx, sql, b.args...)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
+	page.Tasks, err = collectTasks(rows)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
+	if query.Limit > 0 && len(page.Tasks) > query.Limit {
+		page.Tasks = page.Tasks[:query.Limit]
+		page.NextCursor = encodeCursor(page.Tasks[query.Limit-1], field, desc)
+	}
+	return page, nil
+}
+
+// Шаблон ILIKE для поиска подстроки: %, _ и \ из запроса ищутся как есть,
+// как в Storage
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func likePattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// Добавляет в запрос условия по фильтрам TaskQuery
+func applyTaskFilters(b *sqlBuilder, query models.TaskQuery) {
+	if query.Title != "" {
+		b.where("title ILIKE " + b.arg(likePattern(query.Title)) + ` ESCAPE '\'`)
+	}
+	if query.Description != "" {
+		b.where("description ILIKE " + b.arg(likePattern(query.Description)) + ` ESCAPE '\'`)
+	}
+	if len(query.Status) > 0 {
+		statuses := make([]string, len(query.Status))
+		for i, status := range query.Status {
+			statuses[i] = string(status)
+		}
+		b.where("status = ANY(" + b.arg(statuses) + ")")
+	}
+	if len(query.Labels) > 0 {
+		labelFilter(b, query.Labels, query.LabelsMatchAll)
+	}
+	if query.AssigneeID != "" {
+		b.where("assignee_id = " + b.arg(query.AssigneeID))
+	}
+	if query.ReporterID != "" {
+		b.where("reporter_id = " + b.arg(query.ReporterID))
+	}
+	if query.ProjectID != "" {
+		b.where("project_id = " + b.arg(query.ProjectID))
+	}
+	if query.Filter != nil {
+		b.where(compileFilter(b, query.Filter))
+	}
+}
+
+func (db *DBstorage) GetTaskByID(id string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row := db.conn.QueryRow(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id=$1 AND deleted_at IS NULL", id)
+	task, err := scanTask(row)
+	if err != nil {
+		return models.Task{}, notFound(err)
 	}
 	return task, nil
 }
 
-func (db *DBstorage) AddTask(task models.Task) (string, error) {
+func (db *DBstorage) AddTask(task models.Task, actor string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	query := "INSERT INTO users (title, descrtiption) VALUES ($1, $2) RETURNING id"
-	var taskID string
-	err := db.conn.QueryRow(ctx, query, task.Title, task.Description).Scan(&taskID)
+	tx, err := db.conn.Begin(ctx)
 	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	taskID, err := insertTask(ctx, tx, task, actor)
+	if err != nil {
+		return "", err
+	}
+	return taskID, tx.Commit(ctx)
+}
+
+// Вставляет новую задачу в транзакции tx и пишет её в журнал. Общий шаг AddTask
+// и пакетных добавлений (дерево, импорт)
+func insertTask(ctx context.Context, tx pgx.Tx, task models.Task, actor string) (string, error) {
+	if task.Status == "" {
+		task.Status = models.StatusTodo
+	}
+	// Внешний ключ не видит корзину, поэтому родителя проверяем отдельно.
+	// У новой задачи ещё нет ID, и цикла с ней быть не может
+	if task.ParentID != nil {
+		if err := checkParent(ctx, tx, uuid.Nil.String(), *task.ParentID); err != nil {
+			return "", err
+		}
+	}
+	// Новая задача проекта встаёт в конец его доски
+	rank, err := endRank(ctx, tx, task.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	// clock_timestamp, а не now(): задачи одной транзакции (импорт, шаблон)
+	// сохраняют порядок добавления
+	query := `INSERT INTO tasks (title, description, status, due_at, remind_at, parent_id, recurrence, assignee_id, reporter_id,
+		project_id, rank, series_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, clock_timestamp()) RETURNING ` + taskColumns
+	created, err := scanTask(tx.QueryRow(ctx, query, task.Title, task.Description, string(task.Status), task.DueAt,
+		task.RemindAt, task.ParentID, task.Recurrence, task.AssigneeID, task.ReporterID, task.ProjectID, rank,
+		newSeries(task.Recurrence)))
+	if err != nil {
+		// Из внешних ключей при вставке может нарушиться только parent_id
+		if errors.Is(notFound(err), models.ErrTaskNotFound) {
+			return "", fmt.Errorf("%w: parent task not found", models.ErrInvalidParent)
+		}
 		return "", fmt.Errorf("failed to insert task: %w", err)
 	}
 	// Проверка, что taskID не пустой
-	if taskID == "" {
-		return "", fmt.Errorf("failed to get userID after insert")
+	if created.ID == "" {
+		return "", fmt.Errorf("failed to get taskID after insert")
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditCreate, nil, created); err != nil {
+		return "", err
 	}
-	return taskID, nil
+	return created.ID, nil
 }
 
-func (db *DBstorage) UpdateTask(id string, task models.Task) error {
+// Сохраняет задачу. Если task.Version не AnyVersion, она должна совпадать с текущей:
+// строка блокируется на время проверки и записи
+func (db *DBstorage) UpdateTask(id string, task models.Task, actor string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := db.conn.Exec(ctx, "UPDATE tasks SET title=$1, description=$2 WHERE id=$3", task.Title, task.Description, id)
+	tx, err := db.conn.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("update task failed: %w", err)
+		return err
 	}
-	return nil
+	defer tx.Rollback(ctx)
+	if task.ParentID != nil {
+		if err := checkParent(ctx, tx, id, *task.ParentID); err != nil {
+			return err
+		}
+	}
+	old, err := scanTask(tx.QueryRow(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id))
+	if err != nil {
+		return notFound(err)
+	}
+	if task.Version != models.AnyVersion && task.Version != old.Version {
+		return models.ErrVersionMismatch
+	}
+	// Задача, перенесённая в другой проект, встаёт в конец его доски
+	rank := old.Rank
+	if !equalID(old.ProjectID, task.ProjectID) {
+		if rank, err = endRank(ctx, tx, task.ProjectID); err != nil {
+			return err
+		}
+	}
+	// При смене сроков сбрасываем отметки об отправленных напоминаниях
+	updated, err := scanTask(tx.QueryRow(ctx, `UPDATE tasks SET title=$1, description=$2, status=$3,
+		reminder_sent_at = CASE WHEN due_at IS DISTINCT FROM $4 OR remind_at IS DISTINCT FROM $5 THEN NULL ELSE reminder_sent_at END,
+		overdue_sent_at = CASE WHEN due_at IS DISTINCT FROM $4 THEN NULL ELSE overdue_sent_at END,
+		due_at=$4, remind_at=$5, parent_id=$6, recurrence=$7, assignee_id=$8, reporter_id=$9,
+		project_id=$10, rank=$11, series_id = COALESCE(series_id, $13), version = version + 1
+		WHERE id=$12
+		RETURNING `+taskColumns,
+		task.Title, task.Description, string(task.Status), task.DueAt, task.RemindAt, task.ParentID, task.Recurrence,
+		task.AssigneeID, task.ReporterID, task.ProjectID, rank, id, keepSeries(old, task)))
+	if err != nil {
+		return fmt.Errorf("update task failed: %w", notFound(err))
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditUpdate, &old, updated); err != nil {
+		return err
+	}
+	if completes(old, updated) {
+		if err := spawnNext(ctx, tx, updated, actor); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
-func (db *DBstorage) DeleteTask(id string) error {
+// Меняет статус задачи, только если текущий статус равен from
+func (db *DBstorage) UpdateTaskStatus(id string, from, to models.TaskStatus, actor string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := db.conn.Exec(ctx, "DELETE FROM tasks WHERE id=$1", id)
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	task, err := scanTask(tx.QueryRow(ctx, `UPDATE tasks SET status=$1, version = version + 1
+		WHERE id=$2 AND status=$3 AND deleted_at IS NULL
+		RETURNING `+taskColumns, string(to), id, string(from)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrStatusConflict
+	}
+	if err != nil {
+		return fmt.Errorf("update task status failed: %w", err)
+	}
+	old := task
+	old.Status = from
+	old.Version--
+	if err := writeAudit(ctx, tx, actor, models.AuditUpdate, &old, task); err != nil {
+		return err
+	}
+	if completes(old, task) {
+		if err := spawnNext(ctx, tx, task, actor); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Переносит задачу в корзину. Подзадачи отправляются в корзину вместе с ней (cascade)
+// или переходят к её родителю. Если version не AnyVersion, она должна совпадать с текущей
+func (db *DBstorage) DeleteTask(id string, version int64, cascade bool, actor string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// Блокируем строку, чтобы версия не изменилась до удаления
+	var current int64
+	if err := tx.QueryRow(ctx, "SELECT version FROM tasks WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&current); err != nil {
+		return notFound(err)
+	}
+	if version != models.AnyVersion && version != current {
+		return models.ErrVersionMismatch
+	}
+	// До удаления задачи были живыми и на версию младше
+	trashed := func(t models.Task) models.Task {
+		t.DeletedAt = nil
+		t.Version--
+		return t
+	}
+	if cascade {
+		// Вся ветка получает одно время удаления, по нему её потом и восстанавливают
+		rows, err := tx.Query(ctx, `WITH RECURSIVE subtree(id) AS (
+				SELECT id FROM tasks WHERE id = $1
+				UNION
+				SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+			)
+			UPDATE tasks SET deleted_at = now(), version = version + 1 WHERE id IN (SELECT id FROM subtree)
+			RETURNING `+taskColumns, id)
+		if err != nil {
+			return fmt.Errorf("delete task failed: %w", err)
+		}
+		tasks, err := collectTasks(rows)
+		if err != nil {
+			return fmt.Errorf("delete task failed: %w", err)
+		}
+		if err := writeAudits(ctx, tx, actor, models.AuditDelete, tasks, trashed); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+
+	rows, err := tx.Query(ctx, `UPDATE tasks SET parent_id = (SELECT parent_id FROM tasks WHERE id=$1), version = version + 1
+		WHERE parent_id=$1 AND deleted_at IS NULL
+		RETURNING `+taskColumns, id)
 	if err != nil {
 		return fmt.Errorf("delete task failed: %w", err)
 	}
+	children, err := collectTasks(rows)
+	if err != nil {
+		return fmt.Errorf("delete task failed: %w", err)
+	}
+	err = writeAudits(ctx, tx, actor, models.AuditUpdate, children, func(t models.Task) models.Task {
+		t.ParentID = &id
+		t.Version--
+		return t
+	})
+	if err != nil {
+		return err
+	}
+	task, err := scanTask(tx.QueryRow(ctx, "UPDATE tasks SET deleted_at = now(), version = version + 1 WHERE id=$1 RETURNING "+taskColumns, id))
+	if err != nil {
+		return fmt.Errorf("delete task failed: %w", err)
+	}
+	if err := writeAudits(ctx, tx, actor, models.AuditDelete, []models.Task{task}, trashed); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Выбирает напоминания, которые пора отправить, и в той же команде помечает их отправленными.
+// По просроченной задаче уходит только напоминание о просрочке.
+// FOR UPDATE SKIP LOCKED не даёт двум репликам забрать одно и то же напоминание
+func (db *DBstorage) ClaimReminders(now time.Time, lead time.Duration, limit int) ([]models.Reminder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var reminders []models.Reminder
+
+	upcoming := `UPDATE tasks SET reminder_sent_at = $1
+		WHERE id IN (
+			SELECT id FROM tasks
+			WHERE reminder_sent_at IS NULL AND deleted_at IS NULL AND status NOT IN ('done', 'cancelled')
+				AND (remind_at <= $1 OR (remind_at IS NULL AND due_at <= $2))
+				AND (due_at IS NULL OR due_at > $1)
+			ORDER BY COALESCE(remind_at, due_at)
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED)
+		RETURNING ` + taskColumns
+	claimed, err := db.claimReminders(ctx, models.ReminderUpcoming, upcoming, now, now.Add(lead), limit)
+	if err != nil {
+		return nil, err
+	}
+	reminders = append(reminders, claimed...)
+
+	overdue := `UPDATE tasks SET overdue_sent_at = $1
+		WHERE id IN (
+			SELECT id FROM tasks
+			WHERE overdue_sent_at IS NULL AND deleted_at IS NULL AND status NOT IN ('done', 'cancelled')
+				AND due_at <= $1
+			ORDER BY due_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED)
+		RETURNING ` + taskColumns
+	claimed, err = db.claimReminders(ctx, models.ReminderOverdue, overdue, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(reminders, claimed...), nil
+}
+
+func (db *DBstorage) claimReminders(ctx context.Context, kind models.ReminderKind, sql string, args ...any) ([]models.Reminder, error) {
+	rows, err := db.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("claim %s reminders failed: %w", kind, err)
+	}
+	defer rows.Close()
+	var reminders []models.Reminder
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, models.Reminder{Kind: kind, Task: task})
+	}
+	return reminders, rows.Err()
+}
+
+// Снимает отметку об отправке, чтобы напоминание ушло при следующем проходе
+func (db *DBstorage) ReleaseReminder(reminder models.Reminder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	column := "reminder_sent_at"
+	if reminder.Kind == models.ReminderOverdue {
+		column = "overdue_sent_at"
+	}
+	_, err := db.conn.Exec(ctx, "UPDATE tasks SET "+column+" = NULL WHERE id=$1", reminder.Task.ID)
+	if err != nil {
+		return fmt.Errorf("release reminder failed: %w", err)
+	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+const attachmentColumns = "id, task_id, name, content_type, size, checksum, uploaded_by, created_at"
+
+// Условие на задачу вложения ($2): она есть и не в корзине
+const liveTask = "EXISTS (SELECT 1 FROM tasks WHERE id = $2 AND deleted_at IS NULL)"
+
+func scanAttachment(row pgx.Row) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.TaskID, &a.Name, &a.ContentType, &a.Size, &a.Checksum, &a.UploadedBy, &a.CreatedAt)
+	return a, err
+}
+
+// Сохраняет описание вложения. ID задаёт вызывающий: под ним уже лежит содержимое
+// в хранилище файлов. Задача должна существовать и не быть в корзине
+func (db *DBstorage) AddAttachment(att models.Attachment) (models.Attachment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row := db.conn.QueryRow(ctx, `INSERT INTO attachments (id, task_id, name, content_type, size, checksum, uploaded_by)
+		SELECT $1::uuid, id, $3::text, $4::text, $5::bigint, $6::text, $7::text FROM tasks
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING `+attachmentColumns, att.ID, att.TaskID, att.Name, att.ContentType, att.Size, att.Checksum, att.UploadedBy)
+	created, err := scanAttachment(row)
+	if err != nil {
+		return models.Attachment{}, notFound(err)
+	}
+	return created, nil
+}
+
+// Вложения задачи в порядке загрузки
+func (db *DBstorage) GetAttachments(taskID string) ([]models.Attachment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var exists bool
+	err := db.conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND deleted_at IS NULL)", taskID).Scan(&exists)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if !exists {
+		return nil, models.ErrTaskNotFound
+	}
+	rows, err := db.conn.Query(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE task_id = $1 ORDER BY created_at, id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("get attachments failed: %w", err)
+	}
+	defer rows.Close()
+	atts := []models.Attachment{}
+	for rows.Next() {
+		att, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, att)
+	}
+	return atts, rows.Err()
+}
+
+// Вложение задачи taskID. Вложения задач в корзине недоступны
+func (db *DBstorage) GetAttachment(taskID, id string) (models.Attachment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	att, err := scanAttachment(db.conn.QueryRow(ctx, `SELECT `+attachmentColumns+` FROM attachments
+		WHERE id = $1 AND task_id = $2 AND `+liveTask, id, taskID))
+	if err != nil {
+		return models.Attachment{}, attachmentError(err)
+	}
+	return att, nil
+}
+
+// Удаляет описание вложения и возвращает его, чтобы можно было удалить содержимое
+func (db *DBstorage) DeleteAttachment(taskID, id string) (models.Attachment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	att, err := scanAttachment(db.conn.QueryRow(ctx, `DELETE FROM attachments
+		WHERE id = $1 AND task_id = $2 AND `+liveTask+`
+		RETURNING `+attachmentColumns, id, taskID))
+	if err != nil {
+		return models.Attachment{}, attachmentError(err)
+	}
+	return att, nil
+}
+
+// Нет строки или неверный UUID - нет вложения
+func attachmentError(err error) error {
+	if err = notFound(err); errors.Is(err, models.ErrTaskNotFound) {
+		return models.ErrAttachmentNotFound
+	}
+	return err
+}
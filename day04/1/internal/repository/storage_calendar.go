@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Условие на владельца календаря ($1): исполнитель или автор задачи
+const calendarUser = "AND (assignee_id = $1 OR reporter_id = $1)"
+
+// Задачи вне корзины со сроком выполнения, где пользователь userID -
+// исполнитель или автор, по сроку
+func (db *DBstorage) GetCalendarTasks(userID string) ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+taskColumns+` FROM tasks
+		WHERE due_at IS NOT NULL AND deleted_at IS NULL `+calendarUser+`
+		ORDER BY due_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get calendar tasks failed: %w", err)
+	}
+	return collectTasks(rows)
+}
+
+// Отпечаток набора задач календаря пользователя: меняется при добавлении,
+// изменении и удалении любой из них. Читает только id и version, а не задачи целиком
+func (db *DBstorage) CalendarVersion(userID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var count int
+	var digest string
+	err := db.conn.QueryRow(ctx, `SELECT count(*), coalesce(md5(string_agg(id::text || ':' || version::text, ',' ORDER BY id)), '')
+		FROM tasks WHERE due_at IS NOT NULL AND deleted_at IS NULL `+calendarUser, userID).Scan(&count, &digest)
+	if err != nil {
+		return "", fmt.Errorf("get calendar version failed: %w", err)
+	}
+	return strconv.Itoa(count) + "-" + digest, nil
+}
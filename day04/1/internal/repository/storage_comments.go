@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Комментарии к задачам в Postgres
+type CommentDBstorage struct {
+	conn *pgxpool.Pool
+}
+
+func NewCommentsDB(conn *pgxpool.Pool) CommentDBstorage {
+	return CommentDBstorage{
+		conn: conn,
+	}
+}
+
+const commentColumns = "id, task_id, parent_id, author, body, created_at, updated_at"
+
+func scanComment(row pgx.Row) (models.Comment, error) {
+	var c models.Comment
+	err := row.Scan(&c.ID, &c.TaskID, &c.ParentID, &c.Author, &c.Body, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// Добавляет комментарий. Ответ должен ссылаться на комментарий той же задачи
+func (db *CommentDBstorage) AddComment(comment models.Comment) (models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Родитель из другой задачи отсекается условием в SELECT: тогда строк не будет
+	row := db.conn.QueryRow(ctx, `INSERT INTO comments (task_id, parent_id, author, body)
+		SELECT $1::uuid, $2::uuid, $3::text, $4::text
+		WHERE $2::uuid IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = $2 AND task_id = $1)
+		RETURNING `+commentColumns, comment.TaskID, comment.ParentID, comment.Author, comment.Body)
+	created, err := scanComment(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Comment{}, models.ErrInvalidReply
+	}
+	if err != nil {
+		return models.Comment{}, commentError(err)
+	}
+	return created, nil
+}
+
+// Комментарии задачи в порядке написания
+func (db *CommentDBstorage) GetComments(taskID string) ([]models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+commentColumns+" FROM comments WHERE task_id = $1 ORDER BY created_at, id", taskID)
+	if err != nil {
+		return nil, commentError(err)
+	}
+	defer rows.Close()
+	comments := []models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (db *CommentDBstorage) GetCommentByID(id string) (models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	comment, err := scanComment(db.conn.QueryRow(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = $1", id))
+	if err != nil {
+		return models.Comment{}, commentError(err)
+	}
+	return comment, nil
+}
+
+// Меняет текст комментария
+func (db *CommentDBstorage) UpdateComment(id, body string) (models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	comment, err := scanComment(db.conn.QueryRow(ctx, `UPDATE comments SET body = $1, updated_at = now()
+		WHERE id = $2 RETURNING `+commentColumns, body, id))
+	if err != nil {
+		return models.Comment{}, commentError(err)
+	}
+	return comment, nil
+}
+
+// Удаляет комментарий; ответы на него удаляются каскадом
+func (db *CommentDBstorage) DeleteComment(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM comments WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete comment failed: %w", commentError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrCommentNotFound
+	}
+	return nil
+}
+
+// В Postgres комментарии удаляются каскадом вместе с задачей, делать нечего
+func (db *CommentDBstorage) PurgeTasks(context.Context, models.Purged) error {
+	return nil
+}
+
+// Приводит ошибки Postgres к ошибкам комментариев: нет строки или неверный UUID -
+// нет комментария, нарушен внешний ключ на задачу - нет задачи, на родителя - неверный ответ
+func commentError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrCommentNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "22P02":
+			return models.ErrCommentNotFound
+		case pgErr.Code == "23503" && pgErr.ConstraintName == "comments_parent_id_fkey":
+			return models.ErrInvalidReply
+		case pgErr.Code == "23503":
+			return models.ErrTaskNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Добавляет зависимость taskID от dependsOnID, если она не образует цикл.
+// Проверка и вставка идут под advisory-локом, чтобы два параллельных
+// запроса не создали цикл вдвоём
+func (db *DBstorage) AddDependency(taskID, dependsOnID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Иначе задача посчиталась бы ниже одной живой строкой и вышло бы ErrTaskNotFound
+	if taskID == dependsOnID {
+		return models.ErrDependencyCycle
+	}
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('task_dependencies'))"); err != nil {
+		return err
+	}
+	// Обе задачи должны существовать и не быть в корзине
+	var live int
+	err = tx.QueryRow(ctx, "SELECT count(*) FROM tasks WHERE id IN ($1, $2) AND deleted_at IS NULL", taskID, dependsOnID).Scan(&live)
+	if err != nil {
+		return notFound(err)
+	}
+	if live != 2 {
+		return models.ErrTaskNotFound
+	}
+	// Цикл появится, если dependsOnID уже (транзитивно) зависит от taskID
+	var cycle bool
+	err = tx.QueryRow(ctx, `WITH RECURSIVE reach(id) AS (
+			SELECT $1::uuid
+			UNION
+			SELECT d.depends_on FROM task_dependencies d JOIN reach r ON d.task_id = r.id
+		)
+		SELECT EXISTS (SELECT 1 FROM reach WHERE id = $2::uuid)`, dependsOnID, taskID).Scan(&cycle)
+	if err != nil {
+		return notFound(err)
+	}
+	if cycle {
+		return models.ErrDependencyCycle
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO task_dependencies (task_id, depends_on) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		taskID, dependsOnID)
+	if err != nil {
+		return notFound(err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *DBstorage) RemoveDependency(taskID, dependsOnID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM task_dependencies WHERE task_id=$1 AND depends_on=$2", taskID, dependsOnID)
+	if err != nil {
+		return notFound(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrTaskNotFound
+	}
+	return nil
+}
+
+// Задачи, которые блокируют taskID
+func (db *DBstorage) GetDependencies(taskID string) ([]models.Task, error) {
+	if _, err := db.GetTaskByID(taskID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+taskColumns+` FROM tasks
+		WHERE id IN (SELECT depends_on FROM task_dependencies WHERE task_id = $1) AND deleted_at IS NULL
+		ORDER BY created_at, id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
+
+// Предел длины цепочки зависимостей в рекурсивном запросе. Граф ацикличен,
+// предел только страхует от неожиданно глубоких графов
+const maxDependencyDepth = 1000
+
+// Открытые задачи, у которых все блокирующие задачи выполнены,
+// в топологическом порядке: по длине самой длинной цепочки блокирующих задач.
+// UNION убирает повторы (task_id, depth), поэтому ромбы в графе не размножают строки
+func (db *DBstorage) GetActionableTasks() ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `WITH RECURSIVE chain(task_id, depth) AS (
+			SELECT id, 0 FROM tasks WHERE deleted_at IS NULL
+			UNION
+			SELECT d.task_id, c.depth + 1 FROM task_dependencies d JOIN chain c ON d.depends_on = c.task_id
+			WHERE c.depth < $1
+		), levels AS (
+			SELECT task_id, max(depth) AS depth FROM chain GROUP BY task_id
+		)
+		SELECT `+taskColumns+` FROM tasks t JOIN levels l ON l.task_id = t.id
+		WHERE t.deleted_at IS NULL AND t.status NOT IN ('done', 'cancelled')
+			AND NOT EXISTS (
+				SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.depends_on
+				WHERE d.task_id = t.id AND b.status <> 'done' AND b.deleted_at IS NULL)
+		ORDER BY l.depth, t.created_at, t.id`, maxDependencyDepth)
+	if err != nil {
+		return nil, fmt.Errorf("get actionable tasks failed: %w", err)
+	}
+	return collectTasks(rows)
+}
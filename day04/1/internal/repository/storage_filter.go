@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+)
+
+// Столбцы полей языка фильтров
+var filterColumns = map[string]string{
+	"title":       "title",
+	"description": "description",
+	"status":      "status",
+	"assignee":    "assignee_id",
+	"reporter":    "reporter_id",
+	"project":     "project_id",
+	"due":         "due_at",
+	"remind":      "remind_at",
+	"created":     "created_at",
+}
+
+// Операторы сравнения времени с границей интервала [From, To) значения условия
+var timeComparisons = map[filter.Op]string{
+	filter.OpLess:         " < ",
+	filter.OpLessEqual:    " < ",
+	filter.OpGreater:      " >= ",
+	filter.OpGreaterEqual: " >= ",
+}
+
+// Переводит выражение фильтра в условие WHERE; значения уходят в параметры b.
+// Сравнение с NULL даёт NULL, поэтому под NOT оно приводится к false - так же,
+// как пустое поле не подходит под условие в Storage
+func compileFilter(b *sqlBuilder, expr filter.Expr) string {
+	switch e := expr.(type) {
+	case *filter.And:
+		return "(" + compileFilter(b, e.Left) + " AND " + compileFilter(b, e.Right) + ")"
+	case *filter.Or:
+		return "(" + compileFilter(b, e.Left) + " OR " + compileFilter(b, e.Right) + ")"
+	case *filter.Not:
+		return "NOT coalesce(" + compileFilter(b, e.Expr) + ", false)"
+	case *filter.Cond:
+		return compileCond(b, e)
+	}
+	return "false"
+}
+
+func compileCond(b *sqlBuilder, cond *filter.Cond) string {
+	column := filterColumns[cond.Field]
+	switch cond.Field {
+	case "title", "description":
+		if cond.Op == filter.OpContains {
+			return "strpos(lower(" + column + "), lower(" + b.arg(cond.Value) + ")) > 0"
+		}
+		return "lower(" + column + ") = lower(" + b.arg(cond.Value) + ")"
+	case "status":
+		closed := []string{string(models.StatusDone), string(models.StatusCancelled)}
+		switch cond.Value {
+		case filter.StatusOpen:
+			return "NOT (status = ANY(" + b.arg(closed) + "))"
+		case filter.StatusClosed:
+			return "status = ANY(" + b.arg(closed) + ")"
+		}
+		return "status = " + b.arg(cond.Value)
+	case "label":
+		return "id IN (SELECT tl.task_id FROM task_labels tl JOIN labels l ON l.id = tl.label_id WHERE l.name = " +
+			b.arg(cond.Value) + ")"
+	}
+	if cond.None {
+		return column + " IS NULL"
+	}
+	switch cond.Field {
+	case "assignee", "reporter", "project":
+		return column + " = " + b.arg(cond.Value)
+	}
+	switch cond.Op {
+	case filter.OpEqual:
+		return "(" + column + " >= " + b.arg(cond.From) + " AND " + column + " < " + b.arg(cond.To) + ")"
+	case filter.OpLess, filter.OpGreaterEqual:
+		return column + timeComparisons[cond.Op] + b.arg(cond.From)
+	default:
+		return column + timeComparisons[cond.Op] + b.arg(cond.To)
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+	"github.com/rs/zerolog"
+)
+
+const (
+	filterUser1 = "8a1c3f52-0000-4000-8000-000000000001"
+	filterUser2 = "8a1c3f52-0000-4000-8000-000000000002"
+)
+
+func filterTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func filterRef(s string) *string {
+	return &s
+}
+
+// Задачи с пустыми сроком, напоминанием и исполнителем
+var filterTasks = []models.Task{
+	{Title: "Login page", Status: models.StatusTodo, DueAt: filterTime("2024-03-01T10:00:00Z"),
+		RemindAt: filterTime("2024-02-29T10:00:00Z"), AssigneeID: filterRef(filterUser1)},
+	{Title: "Login API", Status: models.StatusDone},
+	{Title: "Signup", Status: models.StatusInProgress, DueAt: filterTime("2024-03-05T10:00:00Z"),
+		AssigneeID: filterRef(filterUser2)},
+	{Title: "Logout", Status: models.StatusCancelled, RemindAt: filterTime("2024-03-02T10:00:00Z"),
+		AssigneeID: filterRef(filterUser1)},
+	{Title: "Docs", Status: models.StatusTodo, DueAt: filterTime("2024-03-02T12:00:00Z")},
+}
+
+// Пустое поле не подходит под сравнение, и NOT такого условия его пропускает
+var filterNullTests = []struct {
+	query string
+	want  []string
+}{
+	{"NOT due<2024-03-03", []string{"Login API", "Logout", "Signup"}},
+	{"NOT NOT due<2024-03-03", []string{"Docs", "Login page"}},
+	{"-assignee:" + filterUser1, []string{"Docs", "Login API", "Signup"}},
+	{"NOT due:none", []string{"Docs", "Login page", "Signup"}},
+	{"NOT (NOT due:none)", []string{"Login API", "Logout"}},
+	{"NOT (due>2024-03-02 OR assignee:" + filterUser1 + ")", []string{"Docs", "Login API"}},
+	{"NOT remind<=2024-03-01", []string{"Docs", "Login API", "Logout", "Signup"}},
+	{"NOT status:closed title~login", []string{"Login page"}},
+	{"due>=2024-03-02 OR NOT assignee:none", []string{"Docs", "Login page", "Logout", "Signup"}},
+}
+
+type filterStorage interface {
+	AddTask(data models.Task, actor string) (string, error)
+	GetAllTasks(query models.TaskQuery) (models.TaskPage, error)
+}
+
+func addFilterTasks(t *testing.T, stor filterStorage) {
+	for _, task := range filterTasks {
+		if _, err := stor.AddTask(task, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Отсортированные названия задач, подходящих под запрос
+func filterTitles(t *testing.T, stor filterStorage, query string) []string {
+	expr, err := filter.Parse(query)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", query, err)
+	}
+	page, err := stor.GetAllTasks(models.TaskQuery{Filter: expr})
+	if err != nil {
+		t.Fatalf("GetAllTasks(%q): %v", query, err)
+	}
+	titles := []string{}
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+func TestFilterNull(t *testing.T) {
+	zlog := zerolog.Nop()
+	stor := New(&zlog)
+	addFilterTasks(t, stor)
+	for _, tt := range filterNullTests {
+		if got := filterTitles(t, stor, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// Все миграции по порядку номеров
+func allMigrations(t *testing.T) []string {
+	entries, err := os.ReadDir("../../migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// compileFilter выбирает те же задачи, что evalFilter, в том числе с NULL под NOT
+func TestFilterNullMatchesDB(t *testing.T) {
+	db := NewDB(newTestPool(t, allMigrations(t)...))
+	addFilterTasks(t, &db)
+	for _, tt := range filterNullTests {
+		if got := filterTitles(t, &db, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: DBstorage got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Журнал изменений задачи в порядке записи
+func (db *DBstorage) GetHistory(id string) ([]models.AuditRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `SELECT id, task_id, actor, operation, at, version, diff
+		FROM task_history WHERE task_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	defer rows.Close()
+	records := []models.AuditRecord{}
+	for rows.Next() {
+		var r models.AuditRecord
+		if err := rows.Scan(&r.ID, &r.EntityID, &r.Actor, &r.Operation, &r.At, &r.Version, &r.Diff); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, notFound(err)
+	}
+	if len(records) == 0 {
+		return nil, models.ErrTaskNotFound
+	}
+	return records, nil
+}
+
+// Пишет запись журнала в транзакции изменения; old - состояние задачи
+// до изменения (nil при создании)
+func writeAudit(ctx context.Context, tx pgx.Tx, actor string, op models.AuditOperation, old *models.Task, task models.Task) error {
+	var before any
+	if old != nil {
+		before = *old
+	}
+	diff, err := fieldDiff(before, task)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO task_history (task_id, actor, operation, version, diff) VALUES ($1, $2, $3, $4, $5)",
+		task.ID, actor, string(op), task.Version, diff)
+	if err != nil {
+		return fmt.Errorf("write audit failed: %w", err)
+	}
+	return nil
+}
+
+// Пишет запись журнала для каждой задачи, изменённой одной командой.
+// prev восстанавливает состояние задачи до изменения по новому
+func writeAudits(ctx context.Context, tx pgx.Tx, actor string, op models.AuditOperation, tasks []models.Task,
+	prev func(models.Task) models.Task) error {
+	for _, task := range tasks {
+		old := prev(task)
+		if err := writeAudit(ctx, tx, actor, op, &old, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Добавляет задачи пакетом в одной транзакции: либо все, либо ни одной.
+// Ошибка в задаче возвращается как *models.RowError с её номером в пакете
+func (db *DBstorage) ImportTasks(tasks []models.Task, actor string) ([]string, error) {
+	// Пакет может быть большим, пяти секунд одиночного запроса не хватит
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		id, err := insertTask(ctx, tx, task, actor)
+		if err != nil {
+			return nil, &models.RowError{Row: i, Err: err}
+		}
+		ids[i] = id
+	}
+	return ids, tx.Commit(ctx)
+}
+
+// Передаёт в fn задачи вне корзины, подходящие под фильтры query, в порядке создания.
+// Строки читаются курсором по одной и в памяти не копятся; пагинация и сортировка
+// из query не используются. Запрос живёт, пока жив ctx
+func (db *DBstorage) ExportTasks(ctx context.Context, query models.TaskQuery, fn func(models.Task) error) error {
+	b := &sqlBuilder{}
+	b.where("deleted_at IS NULL")
+	applyTaskFilters(b, query)
+	rows, err := db.conn.Query(ctx, "SELECT "+taskColumns+" FROM tasks"+b.clause()+" ORDER BY created_at, id", b.args...)
+	if err != nil {
+		return fmt.Errorf("export tasks failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+func (db *DBstorage) GetLabels() ([]models.Label, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT id, name, color FROM labels ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	return collectLabels(rows)
+}
+
+func (db *DBstorage) AddLabel(label models.Label) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var labelID string
+	err := db.conn.QueryRow(ctx, "INSERT INTO labels (name, color) VALUES ($1, $2) RETURNING id",
+		label.Name, label.Color).Scan(&labelID)
+	if err != nil {
+		return "", labelError(err)
+	}
+	return labelID, nil
+}
+
+func (db *DBstorage) GetLabelByID(id string) (models.Label, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var label models.Label
+	err := db.conn.QueryRow(ctx, "SELECT id, name, color FROM labels WHERE id=$1", id).
+		Scan(&label.ID, &label.Name, &label.Color)
+	if err != nil {
+		return models.Label{}, labelError(err)
+	}
+	return label, nil
+}
+
+func (db *DBstorage) UpdateLabel(id string, label models.Label) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return models.ErrLabelNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "UPDATE labels SET name=$1, color=$2 WHERE id=$3", label.Name, label.Color, id)
+	if err != nil {
+		return labelError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLabelNotFound
+	}
+	return nil
+}
+
+// Удаляет метку из каталога; связи с задачами удаляются каскадом
+func (db *DBstorage) DeleteLabel(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return models.ErrLabelNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM labels WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("delete label failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLabelNotFound
+	}
+	return nil
+}
+
+func (db *DBstorage) AttachLabel(taskID, labelID string) error {
+	if _, err := uuid.Parse(labelID); err != nil {
+		return models.ErrLabelNotFound
+	}
+	// Внешний ключ не видит корзину, поэтому задачу проверяем отдельно
+	if _, err := db.GetTaskByID(taskID); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := db.conn.Exec(ctx, "INSERT INTO task_labels (task_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		taskID, labelID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "task_labels_label_id_fkey" {
+			return models.ErrLabelNotFound
+		}
+		return models.ErrTaskNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("attach label failed: %w", err)
+	}
+	return nil
+}
+
+func (db *DBstorage) DetachLabel(taskID, labelID string) error {
+	if _, err := uuid.Parse(taskID); err != nil {
+		return models.ErrTaskNotFound
+	}
+	if _, err := uuid.Parse(labelID); err != nil {
+		return models.ErrLabelNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM task_labels WHERE task_id=$1 AND label_id=$2", taskID, labelID)
+	if err != nil {
+		return fmt.Errorf("detach label failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLabelNotFound
+	}
+	return nil
+}
+
+func (db *DBstorage) GetTaskLabels(taskID string) ([]models.Label, error) {
+	if _, err := db.GetTaskByID(taskID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `SELECT l.id, l.name, l.color FROM labels l
+		JOIN task_labels tl ON tl.label_id = l.id
+		WHERE tl.task_id = $1 ORDER BY l.name`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return collectLabels(rows)
+}
+
+// Условие фильтра по меткам для GET /tasks
+func labelFilter(b *sqlBuilder, names []string, all bool) {
+	// Повторы в запросе не должны увеличивать число требуемых меток
+	names = slices.Clone(names)
+	slices.Sort(names)
+	names = slices.Compact(names)
+	cond := "id IN (SELECT tl.task_id FROM task_labels tl JOIN labels l ON l.id = tl.label_id WHERE l.name = ANY(" +
+		b.arg(names) + ")"
+	if all {
+		cond += " GROUP BY tl.task_id HAVING count(DISTINCT l.name) = " + b.arg(len(names))
+	}
+	b.where(cond + ")")
+}
+
+func collectLabels(rows pgx.Rows) ([]models.Label, error) {
+	defer rows.Close()
+	labels := []models.Label{}
+	for rows.Next() {
+		var label models.Label
+		if err := rows.Scan(&label.ID, &label.Name, &label.Color); err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+	return labels, rows.Err()
+}
+
+func labelError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrLabelNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return models.ErrLabelExists
+	}
+	return err
+}
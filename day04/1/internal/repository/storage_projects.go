@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/rank"
+)
+
+const projectColumns = "id, name, description, created_at"
+
+const boardColumns = "id, project_id, name, columns, created_at"
+
+func scanProject(row pgx.Row) (models.Project, error) {
+	var p models.Project
+	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.CreatedAt)
+	return p, err
+}
+
+func scanBoard(row pgx.Row) (models.Board, error) {
+	var b models.Board
+	err := row.Scan(&b.ID, &b.ProjectID, &b.Name, &b.Columns, &b.CreatedAt)
+	return b, err
+}
+
+func projectNotFound(err error) error {
+	if errors.Is(notFound(err), models.ErrTaskNotFound) {
+		return models.ErrProjectNotFound
+	}
+	return err
+}
+
+func boardNotFound(err error) error {
+	if errors.Is(notFound(err), models.ErrTaskNotFound) {
+		return models.ErrBoardNotFound
+	}
+	return err
+}
+
+func (db *DBstorage) GetProjects() ([]models.Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+projectColumns+" FROM projects ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("get projects failed: %w", err)
+	}
+	defer rows.Close()
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+func (db *DBstorage) AddProject(project models.Project) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	created, err := scanProject(db.conn.QueryRow(ctx, `INSERT INTO projects (name, description)
+		VALUES ($1, $2) RETURNING `+projectColumns, project.Name, project.Description))
+	if err != nil {
+		return models.Project{}, fmt.Errorf("add project failed: %w", err)
+	}
+	return created, nil
+}
+
+func (db *DBstorage) GetProject(id string) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	project, err := scanProject(db.conn.QueryRow(ctx, "SELECT "+projectColumns+" FROM projects WHERE id=$1", id))
+	if err != nil {
+		return models.Project{}, projectNotFound(err)
+	}
+	return project, nil
+}
+
+func (db *DBstorage) UpdateProject(id string, project models.Project) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updated, err := scanProject(db.conn.QueryRow(ctx, `UPDATE projects SET name=$1, description=$2
+		WHERE id=$3 RETURNING `+projectColumns, project.Name, project.Description, id))
+	if err != nil {
+		return models.Project{}, projectNotFound(err)
+	}
+	return updated, nil
+}
+
+// Удаляет проект с его досками. Задачи остаются, но выходят из проекта
+func (db *DBstorage) DeleteProject(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "UPDATE tasks SET project_id = NULL, rank = '' WHERE project_id=$1", id); err != nil {
+		return projectNotFound(err)
+	}
+	tag, err := tx.Exec(ctx, "DELETE FROM projects WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("delete project failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrProjectNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *DBstorage) GetBoards(projectID string) ([]models.Board, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := db.GetProject(projectID); err != nil {
+		return nil, err
+	}
+	rows, err := db.conn.Query(ctx, "SELECT "+boardColumns+" FROM boards WHERE project_id=$1 ORDER BY created_at, id", projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get boards failed: %w", err)
+	}
+	defer rows.Close()
+	boards := []models.Board{}
+	for rows.Next() {
+		board, err := scanBoard(rows)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, board)
+	}
+	return boards, rows.Err()
+}
+
+func (db *DBstorage) AddBoard(board models.Board) (models.Board, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	created, err := scanBoard(db.conn.QueryRow(ctx, `INSERT INTO boards (project_id, name, columns)
+		VALUES ($1, $2, $3) RETURNING `+boardColumns, board.ProjectID, board.Name, board.Columns))
+	if err != nil {
+		// Внешний ключ на projects
+		return models.Board{}, projectNotFound(err)
+	}
+	return created, nil
+}
+
+func (db *DBstorage) GetBoard(id string) (models.Board, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	board, err := scanBoard(db.conn.QueryRow(ctx, "SELECT "+boardColumns+" FROM boards WHERE id=$1", id))
+	if err != nil {
+		return models.Board{}, boardNotFound(err)
+	}
+	return board, nil
+}
+
+// Меняет название и колонки доски; проект доски не меняется
+func (db *DBstorage) UpdateBoard(id string, board models.Board) (models.Board, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updated, err := scanBoard(db.conn.QueryRow(ctx, `UPDATE boards SET name=$1, columns=$2
+		WHERE id=$3 RETURNING `+boardColumns, board.Name, board.Columns, id))
+	if err != nil {
+		return models.Board{}, boardNotFound(err)
+	}
+	return updated, nil
+}
+
+func (db *DBstorage) DeleteBoard(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM boards WHERE id=$1", id)
+	if err != nil {
+		return boardNotFound(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrBoardNotFound
+	}
+	return nil
+}
+
+// Задачи проекта вне корзины в порядке рангов
+func (db *DBstorage) GetProjectTasks(projectID string) ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := db.GetProject(projectID); err != nil {
+		return nil, err
+	}
+	rows, err := db.conn.Query(ctx, "SELECT "+taskColumns+` FROM tasks
+		WHERE project_id=$1 AND deleted_at IS NULL ORDER BY rank, id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project tasks failed: %w", err)
+	}
+	return collectTasks(rows)
+}
+
+// Переставляет карточку: одним UPDATE меняет ранг задачи и, если нужно, статус.
+// Строка проекта блокируется, чтобы соседи не сдвинулись между чтением и записью
+func (db *DBstorage) MoveTask(id string, move models.TaskMove, actor string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return models.Task{}, err
+	}
+	defer tx.Rollback(ctx)
+	old, err := scanTask(tx.QueryRow(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id))
+	if err != nil {
+		return models.Task{}, notFound(err)
+	}
+	if old.Status != move.From {
+		return models.Task{}, models.ErrStatusConflict
+	}
+	if old.ProjectID == nil {
+		return models.Task{}, fmt.Errorf("%w: task has no project", models.ErrInvalidPosition)
+	}
+	if err := lockProject(ctx, tx, *old.ProjectID); err != nil {
+		return models.Task{}, err
+	}
+	r, err := placeCard(ctx, tx, old, move.After, move.Before)
+	if err != nil {
+		return models.Task{}, err
+	}
+	task, err := scanTask(tx.QueryRow(ctx, `UPDATE tasks SET status=$1, rank=$2, version = version + 1
+		WHERE id=$3 RETURNING `+taskColumns, string(move.To), r, id))
+	if err != nil {
+		return models.Task{}, fmt.Errorf("move task failed: %w", err)
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditUpdate, &old, task); err != nil {
+		return models.Task{}, err
+	}
+	if completes(old, task) {
+		if err := spawnNext(ctx, tx, task, actor); err != nil {
+			return models.Task{}, err
+		}
+	}
+	return task, tx.Commit(ctx)
+}
+
+// Ранг между соседями after и before в проекте задачи task. Если задан только
+// один сосед, второй - следующая за ним (или предыдущая перед ним) задача проекта
+func placeCard(ctx context.Context, tx pgx.Tx, task models.Task, after, before string) (string, error) {
+	var a, b string
+	var err error
+	if after != "" {
+		if a, err = neighbourRank(ctx, tx, task, after); err != nil {
+			return "", err
+		}
+	}
+	if before != "" {
+		if b, err = neighbourRank(ctx, tx, task, before); err != nil {
+			return "", err
+		}
+	}
+	siblings := "FROM tasks WHERE project_id=$1 AND id<>$2 AND deleted_at IS NULL"
+	switch {
+	case after != "" && before == "":
+		b, err = siblingRank(ctx, tx, "SELECT rank "+siblings+" AND (rank, id) > ($3, $4) ORDER BY rank, id LIMIT 1",
+			*task.ProjectID, task.ID, a, after)
+	case after == "" && before != "":
+		a, err = siblingRank(ctx, tx, "SELECT rank "+siblings+" AND (rank, id) < ($3, $4) ORDER BY rank DESC, id DESC LIMIT 1",
+			*task.ProjectID, task.ID, b, before)
+	case after == "" && before == "":
+		a, err = siblingRank(ctx, tx, "SELECT max(rank) "+siblings, *task.ProjectID, task.ID)
+	}
+	if err != nil {
+		return "", err
+	}
+	r, err := rank.Between(a, b)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", models.ErrInvalidPosition, err)
+	}
+	return r, nil
+}
+
+// Ранг соседней карточки: она должна быть другой задачей того же проекта
+func neighbourRank(ctx context.Context, tx pgx.Tx, task models.Task, id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil || id == task.ID {
+		return "", fmt.Errorf("%w: neighbour is not a card of this project", models.ErrInvalidPosition)
+	}
+	var r string
+	err := tx.QueryRow(ctx, "SELECT rank FROM tasks WHERE id=$1 AND project_id=$2 AND deleted_at IS NULL",
+		id, *task.ProjectID).Scan(&r)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: neighbour is not a card of this project", models.ErrInvalidPosition)
+	}
+	return r, err
+}
+
+// Ранг из запроса на одну строку; пустой, если строки нет
+func siblingRank(ctx context.Context, tx pgx.Tx, query string, args ...any) (string, error) {
+	var r *string
+	err := tx.QueryRow(ctx, query, args...).Scan(&r)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	if r == nil {
+		return "", nil
+	}
+	return *r, nil
+}
+
+// Блокирует строку проекта до конца транзакции: ранги его задач
+// выдаются по одному. Несуществующий проект - ErrInvalidProject
+func lockProject(ctx context.Context, tx pgx.Tx, projectID string) error {
+	var id string
+	err := tx.QueryRow(ctx, "SELECT id FROM projects WHERE id=$1 FOR UPDATE", projectID).Scan(&id)
+	if errors.Is(projectNotFound(err), models.ErrProjectNotFound) {
+		return fmt.Errorf("%w: project not found", models.ErrInvalidProject)
+	}
+	return err
+}
+
+// Ранг для задачи, которая встаёт в конец проекта; проект блокируется
+func endRank(ctx context.Context, tx pgx.Tx, projectID *string) (string, error) {
+	if projectID == nil {
+		return "", nil
+	}
+	if err := lockProject(ctx, tx, *projectID); err != nil {
+		return "", err
+	}
+	last, err := siblingRank(ctx, tx, "SELECT max(rank) FROM tasks WHERE project_id=$1 AND deleted_at IS NULL", *projectID)
+	if err != nil {
+		return "", err
+	}
+	return rank.After(last)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Создаёт следующий экземпляр серии после выполнения task в той же транзакции.
+// Если экземпляр на эту дату уже есть (задачу переоткрыли и выполнили снова),
+// уникальный индекс по (series_id, due_at) не даст создать второй
+func spawnNext(ctx context.Context, tx pgx.Tx, task models.Task, actor string) error {
+	next, ok, err := nextInstance(task)
+	if err != nil || !ok {
+		return err
+	}
+	if next.Rank, err = endRank(ctx, tx, next.ProjectID); err != nil {
+		return err
+	}
+	created, err := scanTask(tx.QueryRow(ctx, `INSERT INTO tasks (title, description, status, due_at, remind_at, parent_id, recurrence, series_id,
+		assignee_id, reporter_id, project_id, rank)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (series_id, due_at) WHERE series_id IS NOT NULL DO NOTHING
+		RETURNING `+taskColumns,
+		next.Title, next.Description, string(next.Status), next.DueAt, next.RemindAt, next.ParentID, next.Recurrence, next.SeriesID,
+		next.AssigneeID, next.ReporterID, next.ProjectID, next.Rank))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("create next occurrence failed: %w", err)
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditCreate, nil, created); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO task_labels (task_id, label_id) SELECT $1, label_id FROM task_labels WHERE task_id = $2",
+		created.ID, task.ID)
+	if err != nil {
+		return fmt.Errorf("copy labels to next occurrence failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Поиск по сгенерированной колонке search (tsvector, GIN-индекс).
+// plainto_tsquery требует совпадения всех слов запроса, как и поиск в Storage
+func (db *DBstorage) SearchTasks(q string, limit int) ([]models.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	b := &sqlBuilder{}
+	query := b.arg(q)
+	// ts_headline не экранирует текст, поэтому совпадения отмечаются символами
+	// из области частного использования (из самого текста они убираются),
+	// а разметка собирается после экранирования в snippet
+	marks := b.arg(snippetStart + snippetStop)
+	title := "translate(title, " + marks + ", '')"
+	description := "translate(description, " + marks + ", '')"
+	sel := `StartSel="` + snippetStart + `", StopSel="` + snippetStop + `"`
+	sql := `SELECT ` + taskColumns + `, ts_rank(search, tsq) AS search_rank,
+			ts_headline('simple', ` + title + `, tsq, ` + b.arg(sel+", HighlightAll=true") + `),
+			ts_headline('simple', ` + description + `, tsq, ` + b.arg(sel+", MaxWords=35, MinWords=15") + `)
+		FROM tasks, plainto_tsquery('simple', ` + query + `) tsq
+		WHERE search @@ tsq AND deleted_at IS NULL
+		ORDER BY search_rank DESC, created_at, id`
+	if limit > 0 {
+		sql += " LIMIT " + b.arg(limit)
+	}
+	rows, err := db.conn.Query(ctx, sql, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("search tasks failed: %w", err)
+	}
+	defer rows.Close()
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var r models.SearchResult
+		var rank float32
+		task, err := scanTask(rows, &rank, &r.TitleSnippet, &r.DescriptionSnippet)
+		if err != nil {
+			return nil, err
+		}
+		r.Task = task
+		r.TitleSnippet = snippet(r.TitleSnippet)
+		r.DescriptionSnippet = snippet(r.DescriptionSnippet)
+		r.Rank = float64(rank)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// Границы совпадений в ответе ts_headline
+const (
+	snippetStart = "\uE000"
+	snippetStop  = "\uE001"
+)
+
+var snippetMarkup = strings.NewReplacer(snippetStart, "<b>", snippetStop, "</b>")
+
+// Экранирует фрагмент для HTML и превращает границы совпадений в <b></b>,
+// как highlight в Storage
+func snippet(s string) string {
+	return snippetMarkup.Replace(html.EscapeString(s))
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Сводка по задачам вне корзины, по всем или по проекту
+func (db *DBstorage) GetTaskStats(projectID string) (models.TaskStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	b := sqlBuilder{}
+	b.where("t.deleted_at IS NULL")
+	if projectID != "" {
+		b.where("t.project_id = " + b.arg(projectID))
+	}
+	stats := newTaskStats()
+	count := func(query string, add func(key string, n int)) error {
+		rows, err := db.conn.Query(ctx, query, b.args...)
+		if err != nil {
+			return fmt.Errorf("get task stats failed: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var key string
+			var n int
+			if err := rows.Scan(&key, &n); err != nil {
+				return err
+			}
+			add(key, n)
+		}
+		return rows.Err()
+	}
+	err := count("SELECT t.status, count(*) FROM tasks t"+b.clause()+" GROUP BY t.status",
+		func(key string, n int) {
+			stats.ByStatus[models.TaskStatus(key)] = n
+			stats.Total += n
+		})
+	if err == nil {
+		err = count("SELECT coalesce(t.assignee_id::text, '"+noAssignee+"'), count(*) FROM tasks t"+b.clause()+" GROUP BY 1",
+			func(key string, n int) { stats.ByAssignee[key] = n })
+	}
+	if err == nil {
+		err = count(`SELECT l.name, count(*) FROM tasks t
+			JOIN task_labels tl ON tl.task_id = t.id
+			JOIN labels l ON l.id = tl.label_id`+b.clause()+" GROUP BY l.name",
+			func(key string, n int) { stats.ByLabel[key] = n })
+	}
+	if err != nil {
+		return models.TaskStats{}, err
+	}
+	return stats, nil
+}
+
+// Число задач в каждом статусе на конец каждого дня query. Журнал читается один раз:
+// оконными функциями для каждой его записи восстанавливается состояние задачи после
+// неё (последние операция создания, удаления, восстановления или очистки, статус и
+// проект) и номер дня, с которого оно действует. Живое состояние даёт +1 своему
+// статусу в день записи и -1 в день следующей записи задачи; суммы этих изменений
+// по дням накапливаются в точки
+func (db *DBstorage) GetTaskFlow(query models.FlowQuery) ([]models.FlowPoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	b := sqlBuilder{}
+	from, to := b.arg(query.From), b.arg(query.To)
+	b.where("s.op IN ('create', 'restore')")
+	b.where("s.status IS NOT NULL")
+	b.where("d.day IS NOT NULL")
+	if query.ProjectID != "" {
+		b.where("s.project = " + b.arg(query.ProjectID))
+	}
+	// Номер дня - первый день, к концу которого запись уже есть; записи до from
+	// действуют с нулевого. Значение, изменённое записью, тянется по задаче до
+	// следующего изменения: группа - число изменений до записи включительно
+	rows, err := db.conn.Query(ctx, `WITH h AS (
+			SELECT task_id, id,
+				floor(extract(epoch FROM at - `+from+`::timestamptz) / 86400)::int AS day,
+				CASE WHEN operation IN ('create', 'delete', 'restore', 'purge') THEN operation END AS op,
+				diff ? 'status' AS has_status, diff->'status'->>'new' AS status,
+				diff ? 'project_id' AS has_project, diff->'project_id'->>'new' AS project
+			FROM task_history
+			WHERE at < `+to+`::timestamptz + interval '24 hours'
+		), g AS (
+			SELECT *,
+				count(op) OVER w AS op_group,
+				count(*) FILTER (WHERE has_status) OVER w AS status_group,
+				count(*) FILTER (WHERE has_project) OVER w AS project_group
+			FROM h
+			WINDOW w AS (PARTITION BY task_id ORDER BY id)
+		), s AS (
+			SELECT day,
+				lead(day) OVER (PARTITION BY task_id ORDER BY id) AS next_day,
+				max(op) OVER (PARTITION BY task_id, op_group) AS op,
+				max(status) OVER (PARTITION BY task_id, status_group) AS status,
+				max(project) OVER (PARTITION BY task_id, project_group) AS project
+			FROM g
+		)
+		SELECT greatest(d.day, 0), s.status, sum(d.delta)
+		FROM s CROSS JOIN LATERAL (VALUES (s.day, 1), (s.next_day, -1)) AS d(day, delta)`+b.clause()+`
+		GROUP BY 1, 2`, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("get task flow failed: %w", err)
+	}
+	defer rows.Close()
+	deltas := make(map[int]map[models.TaskStatus]int)
+	for rows.Next() {
+		var day int
+		var status string
+		var n int
+		if err := rows.Scan(&day, &status, &n); err != nil {
+			return nil, err
+		}
+		if deltas[day] == nil {
+			deltas[day] = make(map[models.TaskStatus]int)
+		}
+		deltas[day][models.TaskStatus(status)] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return accumulateFlow(query, deltas), nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// База для тестов DBstorage: в ней создаётся и после теста удаляется отдельная схема.
+// Без адреса тесты с Postgres пропускаются
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// Пул, у которого search_path - новая пустая схема с таблицами из migrations
+func newTestPool(t *testing.T, migrations ...string) *pgxpool.Pool {
+	addr := os.Getenv(testDatabaseEnv)
+	if addr == "" {
+		t.Skip(testDatabaseEnv + " is not set")
+	}
+	ctx := context.Background()
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	admin, err := pgx.Connect(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close(ctx)
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		admin.Close(ctx)
+	})
+	cfg, err := pgxpool.ParseConfig(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	for _, name := range migrations {
+		sql, err := os.ReadFile("../../migrations/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("migration %s: %v", name, err)
+		}
+	}
+	return pool
+}
+
+// Postgres и хранилище в памяти на одном журнале считают одинаково
+func TestTaskFlowMatchesDB(t *testing.T) {
+	pool := newTestPool(t, "011_task_history.sql")
+	db := NewDB(pool)
+	ctx := context.Background()
+	ids := make([]string, 6)
+	for i := range ids {
+		ids[i] = uuid.NewString()
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for run := 0; run < 20; run++ {
+		records := randomFlowHistory(rnd, func(n int) string { return ids[n] })
+		if _, err := pool.Exec(ctx, "TRUNCATE task_history"); err != nil {
+			t.Fatal(err)
+		}
+		// ID в журнале растут в порядке вставки, как у записей в памяти
+		batch := &pgx.Batch{}
+		for _, record := range records {
+			batch.Queue(`INSERT INTO task_history (task_id, actor, operation, at, version, diff)
+				VALUES ($1, 'test', $2, $3, 1, $4)`, record.EntityID, string(record.Operation), record.At, record.Diff)
+		}
+		if err := pool.SendBatch(ctx, batch).Close(); err != nil {
+			t.Fatal(err)
+		}
+		stor := newFlowStorage(records)
+		for _, project := range flowProjects {
+			query := models.FlowQuery{From: flowFrom, To: flowFrom.Add(6 * flowStep), ProjectID: project}
+			want, err := stor.GetTaskFlow(query)
+			if err != nil {
+				t.Fatalf("Storage.GetTaskFlow: %v", err)
+			}
+			got, err := db.GetTaskFlow(query)
+			if err != nil {
+				t.Fatalf("DBstorage.GetTaskFlow: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("run %d, project %q: DBstorage.GetTaskFlow = %v, Storage.GetTaskFlow = %v", run, project, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Канал уведомлений Postgres, через который экземпляры сервиса обмениваются
+// событиями потока задач
+const streamChannel = "task_events"
+
+// Ключ рекомендательной блокировки, под которой событие получает номер
+const streamLockKey = 0x7461736b_73747265 // "taskstre"
+
+// Отправляет событие в канал, подставляя номер из общей последовательности.
+// Уведомления доходят в порядке фиксации транзакций, а номера выдаются
+// при вызове nextval - без блокировки события двух экземпляров могли бы
+// прийти не по порядку номеров, и Hub пропустил бы меньший номер при
+// продолжении с Last-Event-ID. Блокировка держится до фиксации, поэтому
+// номер и уведомление следующего события появляются только после этого
+func (db *DBstorage) PublishEvent(payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("publish event failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(streamLockKey)); err != nil {
+		return fmt.Errorf("publish event failed: %w", err)
+	}
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1,
+		jsonb_set($2::jsonb, '{id}', to_jsonb(nextval('task_events_id_seq')))::text)`, streamChannel, string(payload))
+	if err != nil {
+		return fmt.Errorf("publish event failed: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Держит отдельное соединение с LISTEN и передаёт уведомления в fn
+func (db *DBstorage) ListenEvents(ctx context.Context, fn func(payload []byte)) error {
+	pooled, err := db.conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("listen events failed: %w", err)
+	}
+	// Соединение в режиме LISTEN не возвращается в пул
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+streamChannel); err != nil {
+		return fmt.Errorf("listen events failed: %w", err)
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for event failed: %w", err)
+		}
+		fn([]byte(n.Payload))
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Шаблоны задач в Postgres. Дерево задач шаблона хранится в jsonb
+type TemplateDBstorage struct {
+	conn *pgxpool.Pool
+}
+
+func NewTemplatesDB(conn *pgxpool.Pool) TemplateDBstorage {
+	return TemplateDBstorage{
+		conn: conn,
+	}
+}
+
+const templateColumns = "id, name, description, tasks, variables, created_at"
+
+func scanTemplate(row pgx.Row) (models.Template, error) {
+	var t models.Template
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.Tasks, &t.Variables, &t.CreatedAt)
+	return t, err
+}
+
+func templateNotFound(err error) error {
+	if errors.Is(notFound(err), models.ErrTaskNotFound) {
+		return models.ErrTemplateNotFound
+	}
+	return err
+}
+
+// Шаблоны в порядке создания
+func (db *TemplateDBstorage) GetTemplates() ([]models.Template, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+templateColumns+" FROM templates ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("get templates failed: %w", err)
+	}
+	defer rows.Close()
+	templates := []models.Template{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (db *TemplateDBstorage) AddTemplate(template models.Template) (models.Template, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	created, err := scanTemplate(db.conn.QueryRow(ctx, `INSERT INTO templates (name, description, tasks, variables)
+		VALUES ($1, $2, $3, $4) RETURNING `+templateColumns,
+		template.Name, template.Description, template.Tasks, template.Variables))
+	if err != nil {
+		return models.Template{}, fmt.Errorf("add template failed: %w", err)
+	}
+	return created, nil
+}
+
+func (db *TemplateDBstorage) GetTemplate(id string) (models.Template, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	template, err := scanTemplate(db.conn.QueryRow(ctx, "SELECT "+templateColumns+" FROM templates WHERE id=$1", id))
+	if err != nil {
+		return models.Template{}, templateNotFound(err)
+	}
+	return template, nil
+}
+
+func (db *TemplateDBstorage) UpdateTemplate(id string, template models.Template) (models.Template, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updated, err := scanTemplate(db.conn.QueryRow(ctx, `UPDATE templates SET name=$1, description=$2, tasks=$3, variables=$4
+		WHERE id=$5 RETURNING `+templateColumns,
+		template.Name, template.Description, template.Tasks, template.Variables, id))
+	if err != nil {
+		return models.Template{}, templateNotFound(err)
+	}
+	return updated, nil
+}
+
+func (db *TemplateDBstorage) DeleteTemplate(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM templates WHERE id=$1", id)
+	if err != nil {
+		return templateNotFound(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Учёт времени по задачам в Postgres
+type TimeDBstorage struct {
+	conn *pgxpool.Pool
+}
+
+func NewTimeEntriesDB(conn *pgxpool.Pool) TimeDBstorage {
+	return TimeDBstorage{
+		conn: conn,
+	}
+}
+
+const timeEntryColumns = "id, task_id, user_name, started_at, ended_at, note, manual"
+
+// Ключи группировки отчёта
+var timesheetKeys = map[models.TimesheetGroup]string{
+	models.TimesheetByTask: "task_id::text",
+	models.TimesheetByUser: "user_name",
+	models.TimesheetByDay:  "to_char(started_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+}
+
+func scanTimeEntry(row pgx.Row) (models.TimeEntry, error) {
+	var e models.TimeEntry
+	err := row.Scan(&e.ID, &e.TaskID, &e.User, &e.StartedAt, &e.EndedAt, &e.Note, &e.Manual)
+	return withSeconds(e, time.Now()), err
+}
+
+// Запускает таймер пользователя по задаче. Второй идущий таймер
+// не даёт вставить частичный уникальный индекс time_entries_running_idx
+func (db *TimeDBstorage) StartTimer(taskID, user string) (models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(db.conn.QueryRow(ctx, `INSERT INTO time_entries (task_id, user_name, started_at)
+		VALUES ($1, $2, now()) RETURNING `+timeEntryColumns, taskID, user))
+	if err != nil {
+		return models.TimeEntry{}, timeError(err, models.ErrTaskNotFound)
+	}
+	return entry, nil
+}
+
+// Останавливает идущий таймер пользователя по задаче
+func (db *TimeDBstorage) StopTimer(taskID, user string) (models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(db.conn.QueryRow(ctx, `UPDATE time_entries SET ended_at = now()
+		WHERE task_id = $1 AND user_name = $2 AND ended_at IS NULL
+		RETURNING `+timeEntryColumns, taskID, user))
+	if err != nil {
+		return models.TimeEntry{}, timeError(err, models.ErrTimerNotRunning)
+	}
+	return entry, nil
+}
+
+// Идущий таймер пользователя (по любой задаче)
+func (db *TimeDBstorage) GetRunningTimer(user string) (models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(db.conn.QueryRow(ctx, "SELECT "+timeEntryColumns+
+		" FROM time_entries WHERE user_name = $1 AND ended_at IS NULL", user))
+	if err != nil {
+		return models.TimeEntry{}, timeError(err, models.ErrTimeEntryNotFound)
+	}
+	return entry, nil
+}
+
+// Добавляет завершённую запись, внесённую вручную
+func (db *TimeDBstorage) AddTimeEntry(entry models.TimeEntry) (models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	created, err := scanTimeEntry(db.conn.QueryRow(ctx, `INSERT INTO time_entries
+		(task_id, user_name, started_at, ended_at, note, manual) VALUES ($1, $2, $3, $4, $5, true)
+		RETURNING `+timeEntryColumns, entry.TaskID, entry.User, entry.StartedAt, entry.EndedAt, entry.Note))
+	if err != nil {
+		return models.TimeEntry{}, timeError(err, models.ErrTaskNotFound)
+	}
+	return created, nil
+}
+
+// Записи задачи в порядке начала
+func (db *TimeDBstorage) GetTimeEntries(taskID string) ([]models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+timeEntryColumns+
+		" FROM time_entries WHERE task_id = $1 ORDER BY started_at, id", taskID)
+	if err != nil {
+		return nil, timeError(err, models.ErrTaskNotFound)
+	}
+	defer rows.Close()
+	entries := []models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (db *TimeDBstorage) GetTimeEntry(id string) (models.TimeEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry, err := scanTimeEntry(db.conn.QueryRow(ctx, "SELECT "+timeEntryColumns+" FROM time_entries WHERE id = $1", id))
+	if err != nil {
+		return models.TimeEntry{}, timeError(err, models.ErrTimeEntryNotFound)
+	}
+	return entry, nil
+}
+
+func (db *TimeDBstorage) DeleteTimeEntry(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM time_entries WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete time entry failed: %w", timeError(err, models.ErrTimeEntryNotFound))
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrTimeEntryNotFound
+	}
+	return nil
+}
+
+// В Postgres записи времени удаляются каскадом вместе с задачей, делать нечего
+func (db *TimeDBstorage) PurgeTasks(context.Context, models.Purged) error {
+	return nil
+}
+
+// Суммы времени по группам в порядке ключей. Идущие таймеры считаются до текущего момента
+func (db *TimeDBstorage) GetTimesheet(query models.TimesheetQuery) ([]models.TimesheetRow, error) {
+	key, ok := timesheetKeys[query.GroupBy]
+	if !ok {
+		key = timesheetKeys[models.TimesheetByTask]
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `SELECT `+key+` AS key,
+			floor(extract(epoch FROM sum(coalesce(ended_at, now()) - started_at)))::bigint, count(*)
+		FROM time_entries
+		WHERE started_at >= $1 AND started_at < $2
+			AND ($3::text = '' OR user_name = $3) AND ($4::text = '' OR task_id::text = $4)
+		GROUP BY key ORDER BY key`, query.From, query.To, query.User, query.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := []models.TimesheetRow{}
+	for rows.Next() {
+		var row models.TimesheetRow
+		if err := rows.Scan(&row.Key, &row.Seconds, &row.Entries); err != nil {
+			return nil, err
+		}
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
+
+// Приводит ошибки Postgres к ошибкам учёта времени: нет строки или неверный UUID -
+// notFound, нарушен внешний ключ - нет задачи, второй идущий таймер - ErrTimerRunning
+func timeError(err, notFound error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "22P02":
+			return notFound
+		case "23503":
+			return models.ErrTaskNotFound
+		case "23505":
+			return models.ErrTimerRunning
+		}
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Задачи в корзине: сначала удалённые последними
+func (db *DBstorage) GetTrash() ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+taskColumns+" FROM tasks WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+	if err != nil {
+		return nil, fmt.Errorf("get trash failed: %w", err)
+	}
+	return collectTasks(rows)
+}
+
+// Возвращает задачу из корзины вместе с подзадачами, удалёнными вместе с ней
+// (с тем же deleted_at). Родитель задачи не должен быть в корзине
+func (db *DBstorage) RestoreTask(id, actor string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return models.Task{}, err
+	}
+	defer tx.Rollback(ctx)
+	// Тот же лок, что и при смене родителя: дерево не должно меняться во время восстановления
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('task_tree'))"); err != nil {
+		return models.Task{}, err
+	}
+	var deletedAt time.Time
+	var parentDeleted bool
+	err = tx.QueryRow(ctx, `SELECT t.deleted_at, coalesce(p.deleted_at IS NOT NULL, false) FROM tasks t
+		LEFT JOIN tasks p ON p.id = t.parent_id
+		WHERE t.id = $1 AND t.deleted_at IS NOT NULL
+		FOR UPDATE OF t`, id).Scan(&deletedAt, &parentDeleted)
+	if err != nil {
+		return models.Task{}, notFound(err)
+	}
+	if parentDeleted {
+		return models.Task{}, models.ErrParentDeleted
+	}
+	rows, err := tx.Query(ctx, `WITH RECURSIVE subtree(id, deleted_at) AS (
+			SELECT id, deleted_at FROM tasks WHERE id = $1
+			UNION
+			SELECT t.id, t.deleted_at FROM tasks t JOIN subtree s ON t.parent_id = s.id AND t.deleted_at = s.deleted_at
+		)
+		UPDATE tasks SET deleted_at = NULL, version = version + 1 WHERE id IN (SELECT id FROM subtree)
+		RETURNING `+taskColumns, id)
+	if err != nil {
+		return models.Task{}, fmt.Errorf("restore task failed: %w", err)
+	}
+	tasks, err := collectTasks(rows)
+	if err != nil {
+		return models.Task{}, fmt.Errorf("restore task failed: %w", err)
+	}
+	err = writeAudits(ctx, tx, actor, models.AuditRestore, tasks, func(t models.Task) models.Task {
+		t.DeletedAt = &deletedAt
+		t.Version--
+		return t
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	for _, task := range tasks {
+		if task.ID == id {
+			return task, tx.Commit(ctx)
+		}
+	}
+	return models.Task{}, models.ErrTaskNotFound
+}
+
+// Окончательно удаляет задачи, попавшие в корзину не позже before.
+// Зависимости и метки удаляются каскадом, журнал изменений сохраняется.
+// Вложения удаляются явно, чтобы вернуть их описания: содержимое убирает вызывающий
+func (db *DBstorage) PurgeDeleted(before time.Time) (models.Purged, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return models.Purged{}, err
+	}
+	defer tx.Rollback(ctx)
+	var purged models.Purged
+	rows, err := tx.Query(ctx, `DELETE FROM attachments
+		WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at <= $1)
+		RETURNING `+attachmentColumns, before)
+	if err != nil {
+		return models.Purged{}, fmt.Errorf("purge attachments failed: %w", err)
+	}
+	for rows.Next() {
+		att, err := scanAttachment(rows)
+		if err != nil {
+			rows.Close()
+			return models.Purged{}, fmt.Errorf("purge attachments failed: %w", err)
+		}
+		purged.Attachments = append(purged.Attachments, att)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Purged{}, fmt.Errorf("purge attachments failed: %w", err)
+	}
+	rows, err = tx.Query(ctx, "DELETE FROM tasks WHERE deleted_at <= $1 RETURNING "+taskColumns, before)
+	if err != nil {
+		return models.Purged{}, fmt.Errorf("purge tasks failed: %w", err)
+	}
+	tasks, err := collectTasks(rows)
+	if err != nil {
+		return models.Purged{}, fmt.Errorf("purge tasks failed: %w", err)
+	}
+	err = writeAudits(ctx, tx, models.SystemActor, models.AuditPurge, tasks, func(t models.Task) models.Task {
+		return t
+	})
+	if err != nil {
+		return models.Purged{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.Purged{}, err
+	}
+	for _, task := range tasks {
+		purged.TaskIDs = append(purged.TaskIDs, task.ID)
+	}
+	return purged, nil
+}
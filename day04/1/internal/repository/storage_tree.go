@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Добавляет деревья задач в одной транзакции: подзадачи узла получают родителем
+// созданную для него задачу. ID - в порядке обхода, родитель раньше подзадач.
+// AddTask открывает свою транзакцию, поэтому задачи вставляются общим с ним insertTask
+func (db *DBstorage) AddTaskTree(roots []*models.TaskNode, actor string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	ids := []string{}
+	var add func(node *models.TaskNode, parentID *string) error
+	add = func(node *models.TaskNode, parentID *string) error {
+		task := node.Task
+		if parentID != nil {
+			task.ParentID = parentID
+		}
+		id, err := insertTask(ctx, tx, task, actor)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		for _, child := range node.Children {
+			if err := add(child, &id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := add(root, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit(ctx)
+}
+
+// Задача и все её потомки одним рекурсивным запросом: корень первым, дальше по уровням
+func (db *DBstorage) GetSubtree(id string) ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0 FROM tasks WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at IS NULL
+		)
+		SELECT `+taskColumns+` FROM tasks JOIN tree USING (id)
+		ORDER BY tree.depth, created_at, id`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	tasks, err := collectTasks(rows)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if len(tasks) == 0 {
+		return nil, models.ErrTaskNotFound
+	}
+	return tasks, nil
+}
+
+// Родитель должен существовать, не быть в корзине и не быть потомком задачи id.
+// Поднимаемся от нового родителя к корню и ищем среди предков саму задачу
+func checkParent(ctx context.Context, tx pgx.Tx, id, parentID string) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('task_tree'))"); err != nil {
+		return err
+	}
+	var found, cycle bool
+	err := tx.QueryRow(ctx, `WITH RECURSIVE up(id, parent_id) AS (
+			SELECT id, parent_id FROM tasks WHERE id = $1 AND deleted_at IS NULL
+			UNION
+			SELECT t.id, t.parent_id FROM tasks t JOIN up ON t.id = up.parent_id
+		)
+		SELECT count(*) > 0, coalesce(bool_or(id = $2::uuid), false) FROM up`, parentID, id).Scan(&found, &cycle)
+	if err != nil {
+		if errors.Is(notFound(err), models.ErrTaskNotFound) {
+			return fmt.Errorf("%w: parent task not found", models.ErrInvalidParent)
+		}
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: parent task not found", models.ErrInvalidParent)
+	}
+	if cycle {
+		return fmt.Errorf("%w: task cannot be its own ancestor", models.ErrInvalidParent)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Сохранённые представления в Postgres
+type ViewDBstorage struct {
+	conn *pgxpool.Pool
+}
+
+func NewViewsDB(conn *pgxpool.Pool) ViewDBstorage {
+	return ViewDBstorage{
+		conn: conn,
+	}
+}
+
+const viewColumns = "id, owner, name, query, sort, columns, shared_with, created_at, updated_at"
+
+func scanView(row pgx.Row) (models.View, error) {
+	var v models.View
+	err := row.Scan(&v.ID, &v.Owner, &v.Name, &v.Query, &v.Sort, &v.Columns, &v.SharedWith, &v.CreatedAt, &v.UpdatedAt)
+	return v, err
+}
+
+func viewNotFound(err error) error {
+	if errors.Is(notFound(err), models.ErrTaskNotFound) {
+		return models.ErrViewNotFound
+	}
+	return err
+}
+
+// Представления пользователя и открытые ему другими, в порядке создания
+func (db *ViewDBstorage) GetViews(user string) ([]models.View, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+viewColumns+` FROM views
+		WHERE owner = $1 OR $1 = ANY(shared_with) ORDER BY created_at, id`, user)
+	if err != nil {
+		return nil, fmt.Errorf("get views failed: %w", err)
+	}
+	defer rows.Close()
+	views := []models.View{}
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+func (db *ViewDBstorage) AddView(view models.View) (models.View, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	created, err := scanView(db.conn.QueryRow(ctx, `INSERT INTO views (owner, name, query, sort, columns, shared_with)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+viewColumns,
+		view.Owner, view.Name, view.Query, view.Sort, view.Columns, view.SharedWith))
+	if err != nil {
+		return models.View{}, fmt.Errorf("add view failed: %w", err)
+	}
+	return created, nil
+}
+
+func (db *ViewDBstorage) GetView(id string) (models.View, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	view, err := scanView(db.conn.QueryRow(ctx, "SELECT "+viewColumns+" FROM views WHERE id=$1", id))
+	if err != nil {
+		return models.View{}, viewNotFound(err)
+	}
+	return view, nil
+}
+
+// Меняет представление; владелец не меняется
+func (db *ViewDBstorage) UpdateView(id string, view models.View) (models.View, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updated, err := scanView(db.conn.QueryRow(ctx, `UPDATE views
+		SET name=$1, query=$2, sort=$3, columns=$4, shared_with=$5, updated_at=now()
+		WHERE id=$6 RETURNING `+viewColumns,
+		view.Name, view.Query, view.Sort, view.Columns, view.SharedWith, id))
+	if err != nil {
+		return models.View{}, viewNotFound(err)
+	}
+	return updated, nil
+}
+
+func (db *ViewDBstorage) DeleteView(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM views WHERE id=$1", id)
+	if err != nil {
+		return viewNotFound(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrViewNotFound
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+// Шаблоны задач в памяти
+type TemplateStorage struct {
+	mu        sync.RWMutex
+	templates map[string]models.Template
+	log       *zerolog.Logger
+}
+
+func NewTemplates(zlog *zerolog.Logger) *TemplateStorage {
+	return &TemplateStorage{
+		templates: make(map[string]models.Template),
+		log:       zlog,
+	}
+}
+
+// Шаблоны в порядке создания
+func (stor *TemplateStorage) GetTemplates() ([]models.Template, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	templates := []models.Template{}
+	for _, template := range stor.templates {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if !templates[i].CreatedAt.Equal(templates[j].CreatedAt) {
+			return templates[i].CreatedAt.Before(templates[j].CreatedAt)
+		}
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+func (stor *TemplateStorage) AddTemplate(template models.Template) (models.Template, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	template.ID = uuid.New().String()
+	template.CreatedAt = time.Now().UTC()
+	stor.templates[template.ID] = template
+	return template, nil
+}
+
+func (stor *TemplateStorage) GetTemplate(id string) (models.Template, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	template, exists := stor.templates[id]
+	if !exists {
+		return models.Template{}, models.ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (stor *TemplateStorage) UpdateTemplate(id string, template models.Template) (models.Template, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.templates[id]
+	if !exists {
+		return models.Template{}, models.ErrTemplateNotFound
+	}
+	template.ID = id
+	template.CreatedAt = old.CreatedAt
+	stor.templates[id] = template
+	return template, nil
+}
+
+func (stor *TemplateStorage) DeleteTemplate(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.templates[id]; !exists {
+		return models.ErrTemplateNotFound
+	}
+	delete(stor.templates, id)
+	return nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+// Учёт времени по задачам в памяти
+type TimeStorage struct {
+	mu      sync.RWMutex
+	entries map[string]models.TimeEntry
+	log     *zerolog.Logger
+}
+
+func NewTimeEntries(zlog *zerolog.Logger) *TimeStorage {
+	return &TimeStorage{
+		entries: make(map[string]models.TimeEntry),
+		log:     zlog,
+	}
+}
+
+// Запускает таймер пользователя по задаче. Если у пользователя уже идёт
+// таймер (по любой задаче) - ErrTimerRunning
+func (stor *TimeStorage) StartTimer(taskID, user string) (models.TimeEntry, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, running := stor.running(user); running {
+		return models.TimeEntry{}, models.ErrTimerRunning
+	}
+	entry := models.TimeEntry{
+		ID:        uuid.New().String(),
+		TaskID:    taskID,
+		User:      user,
+		StartedAt: time.Now().UTC(),
+	}
+	stor.entries[entry.ID] = entry
+	return withSeconds(entry, entry.StartedAt), nil
+}
+
+// Останавливает идущий таймер пользователя по задаче
+func (stor *TimeStorage) StopTimer(taskID, user string) (models.TimeEntry, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	entry, running := stor.running(user)
+	if !running || entry.TaskID != taskID {
+		return models.TimeEntry{}, models.ErrTimerNotRunning
+	}
+	now := time.Now().UTC()
+	entry.EndedAt = &now
+	stor.entries[entry.ID] = entry
+	return withSeconds(entry, now), nil
+}
+
+// Идущий таймер пользователя (по любой задаче)
+func (stor *TimeStorage) GetRunningTimer(user string) (models.TimeEntry, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	entry, running := stor.running(user)
+	if !running {
+		return models.TimeEntry{}, models.ErrTimeEntryNotFound
+	}
+	return withSeconds(entry, time.Now().UTC()), nil
+}
+
+// Добавляет завершённую запись, внесённую вручную
+func (stor *TimeStorage) AddTimeEntry(entry models.TimeEntry) (models.TimeEntry, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	entry.ID = uuid.New().String()
+	entry.Manual = true
+	stor.entries[entry.ID] = entry
+	return withSeconds(entry, time.Now().UTC()), nil
+}
+
+// Записи задачи в порядке начала
+func (stor *TimeStorage) GetTimeEntries(taskID string) ([]models.TimeEntry, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	now := time.Now().UTC()
+	entries := []models.TimeEntry{}
+	for _, entry := range stor.entries {
+		if entry.TaskID == taskID {
+			entries = append(entries, withSeconds(entry, now))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].StartedAt.Equal(entries[j].StartedAt) {
+			return entries[i].StartedAt.Before(entries[j].StartedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+func (stor *TimeStorage) GetTimeEntry(id string) (models.TimeEntry, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	entry, exists := stor.entries[id]
+	if !exists {
+		return models.TimeEntry{}, models.ErrTimeEntryNotFound
+	}
+	return withSeconds(entry, time.Now().UTC()), nil
+}
+
+func (stor *TimeStorage) DeleteTimeEntry(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.entries[id]; !exists {
+		return models.ErrTimeEntryNotFound
+	}
+	delete(stor.entries, id)
+	return nil
+}
+
+// Удаляет записи окончательно удалённых задач. Вызывается после очистки корзины
+func (stor *TimeStorage) PurgeTasks(_ context.Context, purged models.Purged) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	tasks := make(map[string]bool, len(purged.TaskIDs))
+	for _, id := range purged.TaskIDs {
+		tasks[id] = true
+	}
+	for id, entry := range stor.entries {
+		if tasks[entry.TaskID] {
+			delete(stor.entries, id)
+		}
+	}
+	return nil
+}
+
+// Суммы времени по группам в порядке ключей. Идущие таймеры считаются до текущего момента
+func (stor *TimeStorage) GetTimesheet(query models.TimesheetQuery) ([]models.TimesheetRow, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	now := time.Now().UTC()
+	durations := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	for _, entry := range stor.entries {
+		if entry.StartedAt.Before(query.From) || !entry.StartedAt.Before(query.To) ||
+			query.User != "" && entry.User != query.User ||
+			query.TaskID != "" && entry.TaskID != query.TaskID {
+			continue
+		}
+		var key string
+		switch query.GroupBy {
+		case models.TimesheetByUser:
+			key = entry.User
+		case models.TimesheetByDay:
+			key = entry.StartedAt.UTC().Format(time.DateOnly)
+		default:
+			key = entry.TaskID
+		}
+		durations[key] += entryEnd(entry, now).Sub(entry.StartedAt)
+		counts[key]++
+	}
+	rows := []models.TimesheetRow{}
+	for key, d := range durations {
+		rows = append(rows, models.TimesheetRow{Key: key, Seconds: int64(d / time.Second), Entries: counts[key]})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+	return rows, nil
+}
+
+// Идущий таймер пользователя. Вызывается под stor.mu
+func (stor *TimeStorage) running(user string) (models.TimeEntry, bool) {
+	for _, entry := range stor.entries {
+		if entry.User == user && entry.EndedAt == nil {
+			return entry, true
+		}
+	}
+	return models.TimeEntry{}, false
+}
+
+// Конец записи; у идущего таймера - now
+func entryEnd(entry models.TimeEntry, now time.Time) time.Time {
+	if entry.EndedAt != nil {
+		return *entry.EndedAt
+	}
+	return now
+}
+
+// Заполняет длительность записи на момент now
+func withSeconds(entry models.TimeEntry, now time.Time) models.TimeEntry {
+	entry.Seconds = int64(entryEnd(entry, now).Sub(entry.StartedAt) / time.Second)
+	return entry
+}
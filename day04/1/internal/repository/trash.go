@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Задачи в корзине: сначала удалённые последними
+func (stor *Storage) GetTrash() ([]models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	tasks := []models.Task{}
+	for _, task := range stor.trash {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return lessTrashed(tasks[i], tasks[j])
+	})
+	return tasks, nil
+}
+
+// Возвращает задачу из корзины вместе с подзадачами, удалёнными вместе с ней
+func (stor *Storage) RestoreTask(id, actor string) (models.Task, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	task, exists := stor.trash[id]
+	if !exists {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	if task.ParentID != nil {
+		if _, deleted := stor.trash[*task.ParentID]; deleted {
+			return models.Task{}, models.ErrParentDeleted
+		}
+	}
+	deletedAt := *task.DeletedAt
+	restored := []string{id}
+	for i := 0; i < len(restored); i++ {
+		for childID, child := range stor.trash {
+			if child.ParentID != nil && *child.ParentID == restored[i] && child.DeletedAt.Equal(deletedAt) {
+				restored = append(restored, childID)
+			}
+		}
+	}
+	for _, taskID := range restored {
+		old := stor.trash[taskID]
+		t := old
+		t.DeletedAt = nil
+		t.Version++
+		if err := stor.audit(actor, models.AuditRestore, &old, t); err != nil {
+			return models.Task{}, err
+		}
+		delete(stor.trash, taskID)
+		stor.db[taskID] = t
+		stor.index.add(t)
+	}
+	stor.log.Debug().Any("db", stor.db).Msg("Check db after restore task")
+	return stor.db[id], nil
+}
+
+// Окончательно удаляет задачи, попавшие в корзину не позже before.
+// Журнал изменений задач сохраняется
+func (stor *Storage) PurgeDeleted(before time.Time) (models.Purged, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	var purged models.Purged
+	for id, task := range stor.trash {
+		if !task.DeletedAt.After(before) {
+			if err := stor.audit(models.SystemActor, models.AuditPurge, &task, task); err != nil {
+				return purged, err
+			}
+			purged.Attachments = append(purged.Attachments, stor.purgeTask(id)...)
+			purged.TaskIDs = append(purged.TaskIDs, id)
+		}
+	}
+	return purged, nil
+}
+
+// Порядок задач в корзине: по времени удаления по убыванию, при равенстве - по ID.
+// Совпадает с ORDER BY deleted_at DESC, id в DBstorage
+func lessTrashed(a, b models.Task) bool {
+	if !a.DeletedAt.Equal(*b.DeletedAt) {
+		return a.DeletedAt.After(*b.DeletedAt)
+	}
+	return a.ID < b.ID
+}
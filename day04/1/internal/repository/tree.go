@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Добавляет деревья задач: подзадачи узла получают родителем созданную для него задачу.
+// Добавляются либо все задачи, либо ни одной. ID - в порядке обхода, родитель раньше подзадач.
+// AddTask берёт stor.mu сам, поэтому задачи добавляются общим с ним addTask под одной
+// блокировкой, а его проверки выполняются заранее для всего дерева
+func (stor *Storage) AddTaskTree(roots []*models.TaskNode, actor string) ([]string, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	// Не пройти при добавлении могут только родитель корня и проект, поэтому
+	// проверяем их заранее и дальше добавляем без откатов
+	var check func(node *models.TaskNode) error
+	check = func(node *models.TaskNode) error {
+		if err := stor.checkProject(node.ProjectID); err != nil {
+			return err
+		}
+		for _, child := range node.Children {
+			if err := check(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := stor.checkParent("", root.ParentID); err != nil {
+			return nil, err
+		}
+		if err := check(root); err != nil {
+			return nil, err
+		}
+	}
+	ids := []string{}
+	var add func(node *models.TaskNode, parentID *string) error
+	add = func(node *models.TaskNode, parentID *string) error {
+		task := node.Task
+		if parentID != nil {
+			task.ParentID = parentID
+		}
+		id, err := stor.addTask(task, actor)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		for _, child := range node.Children {
+			if err := add(child, &id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := add(root, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// Задача и все её потомки: корень первым, дальше по уровням
+func (stor *Storage) GetSubtree(id string) ([]models.Task, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	root, exists := stor.db[id]
+	if !exists {
+		return nil, models.ErrTaskNotFound
+	}
+	tasks := []models.Task{root}
+	for i := 0; i < len(tasks); i++ {
+		tasks = append(tasks, stor.children(tasks[i].ID)...)
+	}
+	return tasks, nil
+}
+
+// Прямые подзадачи в порядке создания. Вызывается под stor.mu
+func (stor *Storage) children(id string) []models.Task {
+	var children []models.Task
+	for _, task := range stor.db {
+		if task.ParentID != nil && *task.ParentID == id {
+			children = append(children, task)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return lessTask(children[i], children[j], "created_at", false)
+	})
+	return children
+}
+
+func (stor *Storage) trashSubtree(id string, at time.Time, actor string) error {
+	for _, child := range stor.children(id) {
+		if err := stor.trashSubtree(child.ID, at, actor); err != nil {
+			return err
+		}
+	}
+	return stor.trashTask(id, at, actor)
+}
+
+// Родитель должен существовать и не быть потомком задачи id
+// (для новой задачи id пустой). Вызывается под stor.mu
+func (stor *Storage) checkParent(id string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+	for cur := *parentID; ; {
+		if cur == id {
+			return fmt.Errorf("%w: task cannot be its own ancestor", models.ErrInvalidParent)
+		}
+		parent, exists := stor.db[cur]
+		if !exists {
+			if cur == *parentID {
+				return fmt.Errorf("%w: parent task not found", models.ErrInvalidParent)
+			}
+			return nil
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		cur = *parent.ParentID
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+// Сохранённые представления в памяти
+type ViewStorage struct {
+	mu    sync.RWMutex
+	views map[string]models.View
+	log   *zerolog.Logger
+}
+
+func NewViews(zlog *zerolog.Logger) *ViewStorage {
+	return &ViewStorage{
+		views: make(map[string]models.View),
+		log:   zlog,
+	}
+}
+
+// Представления пользователя и открытые ему другими, в порядке создания
+func (stor *ViewStorage) GetViews(user string) ([]models.View, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	views := []models.View{}
+	for _, view := range stor.views {
+		if view.Owner == user || slices.Contains(view.SharedWith, user) {
+			views = append(views, view)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if !views[i].CreatedAt.Equal(views[j].CreatedAt) {
+			return views[i].CreatedAt.Before(views[j].CreatedAt)
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views, nil
+}
+
+func (stor *ViewStorage) AddView(view models.View) (models.View, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	view.ID = uuid.New().String()
+	view.CreatedAt = time.Now().UTC()
+	view.UpdatedAt = view.CreatedAt
+	stor.views[view.ID] = view
+	return view, nil
+}
+
+func (stor *ViewStorage) GetView(id string) (models.View, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	view, exists := stor.views[id]
+	if !exists {
+		return models.View{}, models.ErrViewNotFound
+	}
+	return view, nil
+}
+
+// Меняет представление; владелец не меняется
+func (stor *ViewStorage) UpdateView(id string, view models.View) (models.View, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.views[id]
+	if !exists {
+		return models.View{}, models.ErrViewNotFound
+	}
+	view.ID = id
+	view.Owner = old.Owner
+	view.CreatedAt = old.CreatedAt
+	view.UpdatedAt = time.Now().UTC()
+	stor.views[id] = view
+	return view, nil
+}
+
+func (stor *ViewStorage) DeleteView(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.views[id]; !exists {
+		return models.ErrViewNotFound
+	}
+	delete(stor.views, id)
+	return nil
+}
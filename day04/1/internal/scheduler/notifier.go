@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/logger"
+	"github.com/rs/zerolog"
+)
+
+// Способ доставки напоминаний (лог, почта, мессенджер и т.д.)
+type Notifier interface {
+	Notify(ctx context.Context, reminder models.Reminder) error
+}
+
+// Notifier по умолчанию: пишет напоминания в лог
+type LogNotifier struct {
+	log *zerolog.Logger
+}
+
+func NewLogNotifier(zlog *zerolog.Logger) *LogNotifier {
+	if zlog == nil {
+		zlog = logger.SetupLogger(false)
+	}
+	return &LogNotifier{log: zlog}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, reminder models.Reminder) error {
+	n.log.Info().
+		Str("kind", string(reminder.Kind)).
+		Str("task_id", reminder.Task.ID).
+		Str("title", reminder.Task.Title).
+		Any("due_at", reminder.Task.DueAt).
+		Msg("Task reminder")
+	return nil
+}
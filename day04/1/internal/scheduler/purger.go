@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+type TrashRepository interface {
+	PurgeDeleted(before time.Time) (models.Purged, error)
+}
+
+// Вызывается после очистки, в которой что-то удалено: убирает то, что
+// не удаляется каскадом вместе с задачами
+type PurgeHook func(ctx context.Context, purged models.Purged) error
+
+// Purger периодически окончательно удаляет задачи,
+// которые пролежали в корзине дольше срока хранения
+type Purger struct {
+	repo      TrashRepository
+	interval  time.Duration
+	retention time.Duration
+	hooks     []PurgeHook
+	log       *zerolog.Logger
+}
+
+// interval - период очистки, retention - сколько задача хранится в корзине
+func NewPurger(repo TrashRepository, interval, retention time.Duration, zlog *zerolog.Logger) *Purger {
+	return &Purger{
+		repo:      repo,
+		interval:  interval,
+		retention: retention,
+		log:       zlog,
+	}
+}
+
+// Добавляет обработчик очистки. Вызывать до Run
+func (p *Purger) OnPurge(hook PurgeHook) {
+	p.hooks = append(p.hooks, hook)
+}
+
+// Run работает до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	purged, err := p.repo.PurgeDeleted(time.Now().UTC().Add(-p.retention))
+	if err != nil {
+		p.log.Error().Err(err).Msg("Failed to purge trash")
+		return
+	}
+	if len(purged.TaskIDs) == 0 {
+		return
+	}
+	p.log.Info().Int("count", len(purged.TaskIDs)).Msg("Trash purged")
+	// Задачи уже удалены, поэтому ошибка одного обработчика не останавливает остальные
+	for _, hook := range p.hooks {
+		if err := hook(ctx, purged); err != nil {
+			p.log.Error().Err(err).Msg("Failed to clean up after purge")
+		}
+	}
+}
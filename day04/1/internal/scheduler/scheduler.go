@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/rs/zerolog"
+)
+
+// Сколько напоминаний забирать за один проход
+const batchSize = 100
+
+type Repository interface {
+	ClaimReminders(now time.Time, lead time.Duration, limit int) ([]models.Reminder, error)
+	ReleaseReminder(reminder models.Reminder) error
+}
+
+// Планировщик периодически ищет задачи с наступившими напоминаниями
+// и отправляет их через Notifier
+type Scheduler struct {
+	repo     Repository
+	notifier Notifier
+	interval time.Duration
+	lead     time.Duration
+	log      *zerolog.Logger
+}
+
+// interval - период проверки, lead - за сколько до DueAt напоминать,
+// если у задачи не задан RemindAt
+func New(repo Repository, notifier Notifier, interval, lead time.Duration, zlog *zerolog.Logger) *Scheduler {
+	return &Scheduler{
+		repo:     repo,
+		notifier: notifier,
+		interval: interval,
+		lead:     lead,
+		log:      zlog,
+	}
+}
+
+// Run работает до отмены ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	for {
+		reminders, err := s.repo.ClaimReminders(time.Now().UTC(), s.lead, batchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to claim reminders")
+			return
+		}
+		failed := false
+		for _, reminder := range reminders {
+			if err := s.notifier.Notify(ctx, reminder); err != nil {
+				s.log.Error().Err(err).Str("task_id", reminder.Task.ID).Msg("Failed to send reminder")
+				failed = true
+				// Вернём напоминание, чтобы попробовать ещё раз на следующем тике
+				if err := s.repo.ReleaseReminder(reminder); err != nil {
+					s.log.Error().Err(err).Str("task_id", reminder.Task.ID).Msg("Failed to release reminder")
+				}
+			}
+		}
+		if failed || len(reminders) < batchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
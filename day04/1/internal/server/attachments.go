@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Запас на заголовки multipart сверх размера самого файла
+const multipartOverhead = 1 << 20
+
+// Сколько байт смотрит http.DetectContentType
+const sniffLen = 512
+
+var errChecksumMismatch = errors.New("attachment content does not match its checksum")
+
+// GET /tasks/:id/attachments - вложения задачи в порядке загрузки
+func (s *Server) GetAttachmentsHandler(ctx *gin.Context) {
+	atts, err := s.Db.GetAttachments(ctx.Param("id"))
+	if err != nil {
+		s.attachmentError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List attachments", "attachments": atts})
+}
+
+// POST /tasks/:id/attachments - загрузка файла (multipart, поле file).
+// Тип содержимого определяется по первым байтам файла, к описанию
+// сохраняется SHA-256 содержимого
+func (s *Server) UploadAttachmentHandler(ctx *gin.Context) {
+	taskID := ctx.Param("id")
+	if _, err := s.Db.GetTaskByID(taskID); err != nil {
+		s.attachmentError(ctx, err)
+		return
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, s.MaxAttachmentSize+multipartOverhead)
+	header, err := ctx.FormFile("file")
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) || (err == nil && header.Size > s.MaxAttachmentSize) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "File is too large", "max_size": s.MaxAttachmentSize})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if header.Filename == "" || len(header.Filename) > 255 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": "file name must be 1-255 bytes"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to open upload")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		s.log.Error().Err(err).Msg("Failed to read upload")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	att := models.Attachment{
+		ID:          uuid.New().String(),
+		TaskID:      taskID,
+		Name:        header.Filename,
+		ContentType: http.DetectContentType(head[:n]),
+		Size:        header.Size,
+		UploadedBy:  actor(ctx),
+	}
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head[:n]), file), hash)
+	key := attachmentKey(att)
+	if err := s.Blobs.Put(ctx.Request.Context(), key, body, att.Size, att.ContentType); err != nil {
+		s.log.Error().Err(err).Str("key", key).Msg("Failed to store attachment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to store attachment"})
+		return
+	}
+	att.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	att, err = s.Db.AddAttachment(att)
+	if err != nil {
+		// Описание не сохранилось - содержимое больше никому не нужно
+		if delErr := s.Blobs.Delete(ctx.Request.Context(), key); delErr != nil {
+			s.log.Error().Err(delErr).Str("key", key).Msg("Failed to remove orphaned attachment")
+		}
+		s.attachmentError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Attachment uploaded", "attachment": att})
+}
+
+// GET /tasks/:id/attachments/:attachment - содержимое вложения.
+// Содержимое не меняется, поэтому ETag - его контрольная сумма. Содержимое
+// сверяется с ней при отдаче: при расхождении соединение обрывается до конца ответа
+func (s *Server) DownloadAttachmentHandler(ctx *gin.Context) {
+	att, err := s.Db.GetAttachment(ctx.Param("id"), ctx.Param("attachment"))
+	if err != nil {
+		s.attachmentError(ctx, err)
+		return
+	}
+	tag := `"` + att.Checksum + `"`
+	if matchTag(ctx.GetHeader("If-None-Match"), tag, true) {
+		ctx.Header("ETag", tag)
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	content, err := s.Blobs.Get(ctx.Request.Context(), attachmentKey(att))
+	if err != nil {
+		s.log.Error().Err(err).Str("attachment_id", att.ID).Msg("Failed to read attachment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to read attachment"})
+		return
+	}
+	defer content.Close()
+	body := &checkedReader{r: bufio.NewReader(content), hash: sha256.New(), want: att.Checksum}
+	ctx.DataFromReader(http.StatusOK, att.Size, att.ContentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}),
+		"ETag":                   tag,
+		"X-Content-Type-Options": "nosniff",
+	})
+	if err := ctx.Errors.Last(); err != nil && errors.Is(err.Err, errChecksumMismatch) {
+		s.log.Error().Err(err.Err).Str("attachment_id", att.ID).Msg("Attachment content is corrupted")
+	}
+}
+
+// Считает SHA-256 отдаваемого содержимого и сверяет его с want перед тем,
+// как отдать последнюю порцию. Так клиент при расхождении получает оборванный
+// ответ, а не целый с испорченным содержимым
+type checkedReader struct {
+	r    *bufio.Reader
+	hash hash.Hash
+	want string
+}
+
+func (c *checkedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	if err == nil {
+		// Есть ещё данные или ошибка чтения - она вернётся следующим Read
+		if _, err = c.r.Peek(1); err != io.EOF {
+			return n, nil
+		}
+	}
+	if err == io.EOF && hex.EncodeToString(c.hash.Sum(nil)) != c.want {
+		return 0, errChecksumMismatch
+	}
+	return n, err
+}
+
+// DELETE /tasks/:id/attachments/:attachment
+func (s *Server) DeleteAttachmentHandler(ctx *gin.Context) {
+	att, err := s.Db.DeleteAttachment(ctx.Param("id"), ctx.Param("attachment"))
+	if err != nil {
+		s.attachmentError(ctx, err)
+		return
+	}
+	// Описания уже нет, поэтому ошибка удаления содержимого оставляет лишь мусор в хранилище
+	if err := s.Blobs.Delete(ctx.Request.Context(), attachmentKey(att)); err != nil {
+		s.log.Error().Err(err).Str("attachment_id", att.ID).Msg("Failed to remove attachment content")
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Attachment deleted", "attachment_id": att.ID})
+}
+
+// Удаляет содержимое вложений окончательно удалённых задач.
+// Вызывается после очистки корзины
+func (s *Server) PurgeAttachments(ctx context.Context, purged models.Purged) error {
+	var errs []error
+	for _, att := range purged.Attachments {
+		if err := s.Blobs.Delete(ctx, attachmentKey(att)); err != nil {
+			errs = append(errs, fmt.Errorf("attachment %s: %w", att.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Ключ содержимого вложения в хранилище файлов
+func attachmentKey(att models.Attachment) string {
+	return "tasks/" + att.TaskID + "/" + att.ID
+}
+
+func (s *Server) attachmentError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrTaskNotFound), errors.Is(err, models.ErrAttachmentNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to change attachments")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
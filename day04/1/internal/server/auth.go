@@ -0,0 +1,23 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/lahnasti/GO_praktikum/day04/shared/auth"
+)
+
+// Ключ контекста с именем пользователя из JWT
+const usernameKey = auth.UsernameKey
+
+// Проверяет JWT из заголовка Authorization по общему для сервисов day04 договору
+// (токены выпускает сервис авторизации day04/3) и кладёт имя пользователя
+// из claim username в контекст запроса
+func AuthMiddleware(secret []byte) gin.HandlerFunc {
+	return auth.Middleware(secret)
+}
+
+// Как AuthMiddleware, но принимает токен и в ?access_token=: для /tasks/stream
+// и /tasks/ws, к которым браузер подключается без заголовков
+func StreamAuthMiddleware(secret []byte) gin.HandlerFunc {
+	return auth.QueryMiddleware(secret)
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/ical"
+)
+
+const calendarProdID = "-//GO_praktikum//Tasks//RU"
+
+// Сколько клиент календаря может не перезапрашивать ленту
+const calendarMaxAge = "300"
+
+var errCalendarDisabled = errors.New("calendar feed is disabled: -jwt-secret is not set")
+
+// Статусы задачи в VTODO (RFC 5545, 3.8.1.11)
+var todoStatuses = map[models.TaskStatus]string{
+	models.StatusTodo:       "NEEDS-ACTION",
+	models.StatusInProgress: "IN-PROCESS",
+	models.StatusReview:     "IN-PROCESS",
+	models.StatusDone:       "COMPLETED",
+	models.StatusCancelled:  "CANCELLED",
+}
+
+// POST /calendar/token - ссылка на календарь текущего пользователя.
+// Клиенты календарей не умеют отправлять Authorization, поэтому токен
+// передаётся в самой ссылке. Он подписан ключом JWT, содержит ID пользователя
+// и действует CalendarTokenTTL; отозвать все токены можно сменой ключа
+func (s *Server) CalendarTokenHandler(ctx *gin.Context) {
+	if len(s.CalendarSecret) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": errCalendarDisabled.Error()})
+		return
+	}
+	user, err := s.me(ctx)
+	if err != nil {
+		s.userError(ctx, err, true)
+		return
+	}
+	expires := time.Now().UTC().Add(s.CalendarTokenTTL).Truncate(time.Second)
+	token := calendarToken(s.CalendarSecret, user.ID, expires)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Calendar feed", "url": "/calendar.ics?token=" + token, "token": token, "expires_at": expires})
+}
+
+// GET /calendar.ics?token=...&kind=event|todo - задачи со сроком, где владелец
+// токена исполнитель или автор, в формате iCalendar: событиями (VEVENT,
+// по умолчанию) или задачами (VTODO). Поддерживает If-None-Match, чтобы
+// подписанные клиенты не перечитывали неизменную ленту
+func (s *Server) CalendarHandler(ctx *gin.Context) {
+	if len(s.CalendarSecret) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": errCalendarDisabled.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	userID, ok := verifyCalendarToken(s.CalendarSecret, ctx.Query("token"), now)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid calendar token"})
+		return
+	}
+	kind := ctx.DefaultQuery("kind", "event")
+	if kind != "event" && kind != "todo" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "kind must be event or todo"})
+		return
+	}
+	// Отпечаток читается раньше задач: если задачи успеют измениться, клиент
+	// получит новую ленту со старым ETag и лишь перечитает её в следующий раз
+	version, err := s.Db.CalendarVersion(userID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to get calendar version")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tag := `"` + kind + "-" + version + `"`
+	ctx.Header("ETag", tag)
+	ctx.Header("Cache-Control", "private, max-age="+calendarMaxAge)
+	if matchTag(ctx.GetHeader("If-None-Match"), tag, true) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	tasks, err := s.Db.GetCalendarTasks(userID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to get calendar tasks")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	components := make([]ical.Component, len(tasks))
+	for i, task := range tasks {
+		components[i] = calendarComponent(task, kind, now)
+	}
+	ctx.Header("Content-Type", "text/calendar; charset=utf-8")
+	ctx.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	ctx.Status(http.StatusOK)
+	if err := ical.Write(ctx.Writer, calendarProdID, "Tasks", components); err != nil {
+		s.log.Error().Err(err).Msg("Failed to write calendar")
+	}
+}
+
+// Задача со сроком как VEVENT (момент DTSTART в срок задачи) или VTODO (DUE).
+// now - время создания ленты, оно же DTSTAMP (RFC 5545, 3.8.7.2)
+func calendarComponent(task models.Task, kind string, now time.Time) ical.Component {
+	c := ical.Component{Kind: "VEVENT"}
+	if kind == "todo" {
+		c.Kind = "VTODO"
+	}
+	c.Text("UID", task.ID+"@tasks")
+	c.Time("DTSTAMP", now)
+	c.Time("CREATED", task.CreatedAt)
+	c.Text("SUMMARY", task.Title)
+	if task.Description != "" {
+		c.Text("DESCRIPTION", task.Description)
+	}
+	// Версия задачи начинается с 1, а SEQUENCE - с 0
+	c.Raw("SEQUENCE", strconv.FormatInt(task.Version-1, 10))
+	if kind == "todo" {
+		c.Time("DUE", *task.DueAt)
+		c.Raw("STATUS", todoStatuses[task.Status])
+		return c
+	}
+	c.Time("DTSTART", *task.DueAt)
+	c.Raw("TRANSP", "TRANSPARENT")
+	if task.Status == models.StatusCancelled {
+		c.Raw("STATUS", "CANCELLED")
+	} else {
+		c.Raw("STATUS", "CONFIRMED")
+	}
+	return c
+}
+
+// Токен календаря: ID пользователя со сроком действия и HMAC-SHA256 от них
+func calendarToken(secret []byte, userID string, expires time.Time) string {
+	payload := userID + "|" + strconv.FormatInt(expires.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(calendarMAC(secret, payload))
+}
+
+// ID пользователя из токена, если подпись верна и срок не истёк к now
+func verifyCalendarToken(secret []byte, token string, now time.Time) (string, bool) {
+	encPayload, encMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encMAC)
+	if err != nil || !hmac.Equal(mac, calendarMAC(secret, string(payload))) {
+		return "", false
+	}
+	userID, exp, ok := strings.Cut(string(payload), "|")
+	if !ok || userID == "" {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(expires, 0)) {
+		return "", false
+	}
+	return userID, true
+}
+
+// Префикс отделяет токены календаря от других подписей тем же ключом
+func calendarMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("calendar:" + payload))
+	return mac.Sum(nil)
+}
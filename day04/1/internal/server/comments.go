@@ -0,0 +1,172 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+type commentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+	// Комментарий, на который это ответ (только при создании)
+	ParentID *string `json:"parent_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// GET /tasks/:id/comments - обсуждение задачи: комментарии верхнего уровня
+// с вложенными ответами, в порядке написания
+func (s *Server) GetCommentsHandler(ctx *gin.Context) {
+	task, ok := s.commentedTask(ctx)
+	if !ok {
+		return
+	}
+	comments, err := s.Comments.GetComments(task.ID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to get comments")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task comments", "comments": buildThreads(comments)})
+}
+
+// POST /tasks/:id/comments - новый комментарий или ответ (parent_id).
+// Автор - пользователь из JWT, если авторизация включена
+func (s *Server) AddCommentHandler(ctx *gin.Context) {
+	var req commentRequest
+	if !s.bindComment(ctx, &req) {
+		return
+	}
+	task, ok := s.commentedTask(ctx)
+	if !ok {
+		return
+	}
+	comment, err := s.Comments.AddComment(models.Comment{
+		TaskID:   task.ID,
+		ParentID: req.ParentID,
+		Author:   actor(ctx),
+		Body:     req.Body,
+	})
+	if errors.Is(err, models.ErrInvalidReply) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrTaskNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to save comment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to save comment"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Comment added", "comment": comment})
+}
+
+// PUT /tasks/:id/comments/:comment - правка текста комментария
+func (s *Server) UpdateCommentHandler(ctx *gin.Context) {
+	var req commentRequest
+	if !s.bindComment(ctx, &req) {
+		return
+	}
+	comment, ok := s.ownComment(ctx)
+	if !ok {
+		return
+	}
+	comment, err := s.Comments.UpdateComment(comment.ID, req.Body)
+	if errors.Is(err, models.ErrCommentNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to update comment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment updated", "comment": comment})
+}
+
+// DELETE /tasks/:id/comments/:comment - удаляет комментарий вместе с ответами на него
+func (s *Server) DeleteCommentHandler(ctx *gin.Context) {
+	comment, ok := s.ownComment(ctx)
+	if !ok {
+		return
+	}
+	err := s.Comments.DeleteComment(comment.ID)
+	if errors.Is(err, models.ErrCommentNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to delete comment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment deleted", "comment_id": comment.ID})
+}
+
+func (s *Server) bindComment(ctx *gin.Context, req *commentRequest) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return false
+	}
+	if err := s.Valid.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// Задача из :id. Комментировать можно только задачи вне корзины
+func (s *Server) commentedTask(ctx *gin.Context) (models.Task, bool) {
+	task, err := s.Db.GetTaskByID(ctx.Param("id"))
+	if err != nil {
+		s.log.Error().Err(err).Msg("Not found ID")
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return models.Task{}, false
+	}
+	return task, true
+}
+
+// Комментарий из :comment, принадлежащий задаче :id. Менять и удалять комментарий
+// может только его автор, поэтому без авторизации (пустой -jwt-secret) отвечает 401:
+// иначе любой мог бы править чужие комментарии
+func (s *Server) ownComment(ctx *gin.Context) (models.Comment, bool) {
+	username := ctx.GetString(usernameKey)
+	if username == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"message": "Authorization required", "error": "changing comments needs a signed in user"})
+		return models.Comment{}, false
+	}
+	comment, err := s.Comments.GetCommentByID(ctx.Param("comment"))
+	if err == nil && comment.TaskID != ctx.Param("id") {
+		err = models.ErrCommentNotFound
+	}
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return models.Comment{}, false
+	}
+	if username != comment.Author {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Only the author can change the comment", "error": "forbidden"})
+		return models.Comment{}, false
+	}
+	return comment, true
+}
+
+// Раскладывает комментарии по веткам. Родитель всегда написан раньше ответа,
+// поэтому при обходе в порядке написания он уже есть в nodes
+func buildThreads(comments []models.Comment) []*models.CommentNode {
+	nodes := make(map[string]*models.CommentNode, len(comments))
+	roots := []*models.CommentNode{}
+	for _, comment := range comments {
+		node := &models.CommentNode{Comment: comment}
+		nodes[comment.ID] = node
+		if comment.ParentID != nil {
+			if parent, ok := nodes[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// POST /tasks/:id/dependencies - задача :id блокируется задачей depends_on
+func (s *Server) AddDependencyHandler(ctx *gin.Context) {
+	var dep models.Dependency
+	if err := ctx.ShouldBindJSON(&dep); err != nil {
+		s.log.Error().Err(err).Msg("Failed unmarshal body")
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(dep); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	dep.TaskID = ctx.Param("id")
+	if err := s.Db.AddDependency(dep.TaskID, dep.DependsOnID); err != nil {
+		s.dependencyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Dependency added", "dependency": dep})
+}
+
+// DELETE /tasks/:id/dependencies/:dep
+func (s *Server) RemoveDependencyHandler(ctx *gin.Context) {
+	dep := models.Dependency{TaskID: ctx.Param("id"), DependsOnID: ctx.Param("dep")}
+	if err := s.Db.RemoveDependency(dep.TaskID, dep.DependsOnID); err != nil {
+		s.dependencyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Dependency removed", "dependency": dep})
+}
+
+// GET /tasks/:id/dependencies - задачи, которые блокируют :id
+func (s *Server) GetDependenciesHandler(ctx *gin.Context) {
+	tasks, err := s.Db.GetDependencies(ctx.Param("id"))
+	if err != nil {
+		s.dependencyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List dependencies", "tasks": tasks})
+}
+
+// GET /tasks/actionable - задачи, которые можно брать в работу прямо сейчас
+func (s *Server) GetActionableTasksHandler(ctx *gin.Context) {
+	tasks, err := s.Db.GetActionableTasks()
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed inquiry")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List actionable tasks", "tasks": tasks})
+}
+
+func (s *Server) dependencyError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrTaskNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrDependencyCycle):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Dependency rejected", "error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to change dependencies")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
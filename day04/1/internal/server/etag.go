@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Сильный ETag из номера версии
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Совпадает ли ETag версии с одним из значений заголовка (If-Match, If-None-Match).
+// weak разрешает слабое сравнение (W/"..."), как требуется для If-None-Match
+func matchETag(header string, version int64, weak bool) bool {
+	return matchTag(header, etag(version), weak)
+}
+
+// То же для произвольного ETag tag (в кавычках)
+func matchTag(header, tag string, weak bool) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return true
+		}
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		}
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяет If-Match для изменения сущности версии current.
+// Возвращает версию, которую нужно передать в репозиторий для атомарной проверки,
+// или false, если ответ 412 уже отправлен
+func checkIfMatch(ctx *gin.Context, current int64) (int64, bool) {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return current, true
+	}
+	if !matchETag(header, current, false) {
+		preconditionFailed(ctx, current)
+		return 0, false
+	}
+	return current, true
+}
+
+func preconditionFailed(ctx *gin.Context, current int64) {
+	ctx.Header("ETag", etag(current))
+	ctx.JSON(http.StatusPreconditionFailed, gin.H{
+		"message": "Resource has been modified",
+		"error":   models.ErrVersionMismatch.Error(),
+	})
+}
+
+// Ответ на потерянное обновление: 412, если клиент прислал If-Match, иначе 409
+func versionConflict(ctx *gin.Context) {
+	if ctx.GetHeader("If-Match") != "" {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"message": "Resource has been modified", "error": models.ErrVersionMismatch.Error()})
+		return
+	}
+	ctx.JSON(http.StatusConflict, gin.H{"message": "Resource was modified concurrently, retry", "error": models.ErrVersionMismatch.Error()})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Колонки CSV при экспорте. Импорт принимает те же колонки
+var csvColumns = []string{
+	"id", "title", "description", "status", "created_at", "due_at", "remind_at",
+	"parent_id", "recurrence", "series_id", "version", "assignee_id", "reporter_id",
+	"project_id", "rank",
+}
+
+// Через сколько задач отправлять накопленное клиенту
+const exportFlushEvery = 100
+
+// GET /tasks/export?format=csv|ndjson - все задачи вне корзины в порядке создания.
+// Фильтры те же, что у GET /tasks; ответ пишется по мере чтения из хранилища
+func (s *Server) ExportTasksHandler(ctx *gin.Context) {
+	query, err := parseTaskQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if !s.resolveQueryUsers(ctx, &query) {
+		return
+	}
+	var write func(models.Task) error
+	var flush func() error
+	format := ctx.DefaultQuery("format", "csv")
+	switch format {
+	case "csv":
+		// csv.Writer буферизует: до первого flush клиенту ничего не уходит
+		w := csv.NewWriter(ctx.Writer)
+		w.Write(csvColumns)
+		write = func(task models.Task) error {
+			return w.Write(taskRecord(task))
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	case "ndjson":
+		enc := json.NewEncoder(ctx.Writer)
+		write = func(task models.Task) error {
+			return enc.Encode(task)
+		}
+		flush = func() error { return nil }
+		ctx.Header("Content-Type", "application/x-ndjson")
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "format must be csv or ndjson"})
+		return
+	}
+	ctx.Header("Content-Disposition", `attachment; filename="tasks.`+format+`"`)
+
+	n := 0
+	err = s.Db.ExportTasks(ctx.Request.Context(), query, func(task models.Task) error {
+		if err := write(task); err != nil {
+			return err
+		}
+		if n++; n%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// Заголовки уже могли уйти клиенту: тогда остаётся только оборвать ответ
+		s.log.Error().Err(err).Int("exported", n).Msg("Failed to export tasks")
+		if !ctx.Writer.Written() {
+			// Иначе gin оставил бы text/csv или x-ndjson и у ответа с ошибкой
+			ctx.Header("Content-Type", "")
+			ctx.Header("Content-Disposition", "")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.Writer.Flush()
+}
+
+// Строка CSV для задачи в порядке csvColumns
+func taskRecord(task models.Task) []string {
+	return []string{
+		task.ID,
+		task.Title,
+		task.Description,
+		string(task.Status),
+		task.CreatedAt.Format(time.RFC3339Nano),
+		formatTime(task.DueAt),
+		formatTime(task.RemindAt),
+		stringOrEmpty(task.ParentID),
+		task.Recurrence,
+		stringOrEmpty(task.SeriesID),
+		strconv.FormatInt(task.Version, 10),
+		stringOrEmpty(task.AssigneeID),
+		stringOrEmpty(task.ReporterID),
+		stringOrEmpty(task.ProjectID),
+		task.Rank,
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Автор изменений, если пользователь не известен (авторизация выключена)
+const anonymousActor = "anonymous"
+
+// Кто выполняет запрос - для журнала изменений и авторства комментариев.
+// Только проверенный пользователь из JWT: заголовкам клиента журнал не доверяет
+func actor(ctx *gin.Context) string {
+	if name := ctx.GetString(usernameKey); name != "" {
+		return name
+	}
+	return anonymousActor
+}
+
+// GET /tasks/:id/history - журнал изменений задачи: кто, когда и какие поля менял.
+// Доступен и для задач в корзине, и для окончательно удалённых
+func (s *Server) GetHistoryHandler(ctx *gin.Context) {
+	records, err := s.Db.GetHistory(ctx.Param("id"))
+	if errors.Is(err, models.ErrTaskNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to get task history")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task history", "history": records})
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+const (
+	// Предельный размер тела и число задач в одном импорте
+	maxImportBytes = 32 << 20
+	maxImportRows  = 10000
+	// Предельная длина строки NDJSON
+	maxImportLine = 1 << 20
+)
+
+// Колонки CSV, которые выставляет сервер: при импорте они допускаются
+// (чтобы можно было загрузить собственную выгрузку), но игнорируются
+var csvReadOnly = map[string]bool{"id": true, "created_at": true, "series_id": true, "version": true, "rank": true}
+
+// Задача из файла импорта. Line - номер строки файла, с единицы
+type importRow struct {
+	Line int
+	Task models.Task
+	Err  error
+}
+
+// Ошибка в строке импорта для отчёта
+type importError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// POST /tasks/import?format=csv|ndjson&mode=atomic|best-effort
+// Формат берётся из параметра или Content-Type (text/csv, application/x-ndjson).
+// atomic (по умолчанию) - задачи добавляются, только если все строки корректны;
+// best-effort - добавляются корректные строки, остальные попадают в отчёт
+func (s *Server) ImportTasksHandler(ctx *gin.Context) {
+	format, err := importFormat(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	mode := ctx.DefaultQuery("mode", "atomic")
+	if mode != "atomic" && mode != "best-effort" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "mode must be atomic or best-effort"})
+		return
+	}
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportBytes)
+	var rows []importRow
+	if format == "csv" {
+		rows, err = readCSVTasks(body)
+	} else {
+		rows, err = readNDJSONTasks(body)
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "Import is too large", "max_size": maxImportBytes})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+
+	report := []importError{}
+	var valid []importRow
+	users := make(map[string]bool)
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = s.validateImported(row.Task, users)
+		}
+		if row.Err != nil {
+			report = append(report, importError{Line: row.Line, Error: row.Err.Error()})
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	who := actor(ctx)
+	ids := []string{}
+	if mode == "atomic" {
+		if len(report) > 0 {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Import rejected", "imported": 0, "errors": report})
+			return
+		}
+		tasks := make([]models.Task, len(valid))
+		for i, row := range valid {
+			tasks[i] = row.Task
+		}
+		ids, err = s.Db.ImportTasks(tasks, who)
+		var rowErr *models.RowError
+		if errors.As(err, &rowErr) && (errors.Is(err, models.ErrInvalidParent) || errors.Is(err, models.ErrInvalidProject)) {
+			report = append(report, importError{Line: valid[rowErr.Row].Line, Error: rowErr.Err.Error()})
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Import rejected", "imported": 0, "errors": report})
+			return
+		}
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to import tasks")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import tasks", "error": err.Error()})
+			return
+		}
+	} else {
+		for _, row := range valid {
+			id, err := s.Db.AddTask(row.Task, who)
+			if err != nil {
+				if !errors.Is(err, models.ErrInvalidParent) && !errors.Is(err, models.ErrInvalidProject) {
+					s.log.Error().Err(err).Int("line", row.Line).Msg("Failed to import task")
+				}
+				report = append(report, importError{Line: row.Line, Error: err.Error()})
+				continue
+			}
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		s.emitTask(webhook.TaskCreated, id)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tasks imported", "imported": len(ids), "task_ids": ids, "errors": report})
+}
+
+// Те же проверки, что при создании задачи, кроме статуса: при переносе
+// из другого трекера задача может быть уже начата или закрыта.
+// users - уже проверенные в этом импорте пользователи
+func (s *Server) validateImported(task models.Task, users map[string]bool) error {
+	if err := s.Valid.Struct(task); err != nil {
+		return err
+	}
+	if err := validateDates(task); err != nil {
+		return err
+	}
+	if err := validateRecurrence(task); err != nil {
+		return err
+	}
+	return s.checkUsers(task, users)
+}
+
+func importFormat(ctx *gin.Context) (string, error) {
+	if format := ctx.Query("format"); format != "" {
+		if format != "csv" && format != "ndjson" {
+			return "", errors.New("format must be csv or ndjson")
+		}
+		return format, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return "csv", nil
+	case "application/x-ndjson", "application/jsonl", "application/json-lines":
+		return "ndjson", nil
+	}
+	return "", errors.New("set format=csv|ndjson or Content-Type text/csv or application/x-ndjson")
+}
+
+// Разбирает CSV с заголовком из колонок csvColumns (в любом порядке, не все обязательны).
+// Ошибки в отдельных строках попадают в importRow.Err, ошибка всего файла - в err
+func readCSVTasks(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty CSV")
+	}
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(csvColumns))
+	for _, column := range csvColumns {
+		known[column] = true
+	}
+	seen := make(map[string]bool, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !known[column] {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("duplicate CSV column %q", column)
+		}
+		seen[column] = true
+		header[i] = column
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		// Неверное число полей - ошибка строки, остальные ошибки разбора - всего файла
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("too many rows, at most %d per import", maxImportRows)
+		}
+		row := importRow{Line: line}
+		if err != nil {
+			row.Err = csv.ErrFieldCount
+		} else {
+			row.Task, row.Err = csvTask(header, record)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no tasks to import")
+	}
+	return rows, nil
+}
+
+func csvTask(header, record []string) (models.Task, error) {
+	var task models.Task
+	for i, column := range header {
+		value := record[i]
+		if csvReadOnly[column] || value == "" {
+			continue
+		}
+		switch column {
+		case "title":
+			task.Title = value
+		case "description":
+			task.Description = value
+		case "status":
+			task.Status = models.TaskStatus(value)
+		case "due_at", "remind_at":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return models.Task{}, fmt.Errorf("%s must be RFC 3339 time", column)
+			}
+			if column == "due_at" {
+				task.DueAt = &t
+			} else {
+				task.RemindAt = &t
+			}
+		case "parent_id":
+			task.ParentID = &value
+		case "recurrence":
+			task.Recurrence = value
+		case "assignee_id":
+			task.AssigneeID = &value
+		case "reporter_id":
+			task.ReporterID = &value
+		case "project_id":
+			task.ProjectID = &value
+		}
+	}
+	return task, nil
+}
+
+// Разбирает NDJSON: по задаче в формате JSON на строку, пустые строки пропускаются.
+// Поля, которые выставляет сервер (id, created_at, version и т.д.), игнорируются
+func readNDJSONTasks(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxImportLine)
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("too many rows, at most %d per import", maxImportRows)
+		}
+		var task models.Task
+		err := json.Unmarshal(data, &task)
+		rows = append(rows, importRow{Line: line, Task: models.Task{
+			Title:       task.Title,
+			Description: task.Description,
+			Status:      task.Status,
+			DueAt:       task.DueAt,
+			RemindAt:    task.RemindAt,
+			ParentID:    task.ParentID,
+			Recurrence:  task.Recurrence,
+			AssigneeID:  task.AssigneeID,
+			ReporterID:  task.ReporterID,
+			ProjectID:   task.ProjectID,
+		}, Err: err})
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("line longer than %d bytes", maxImportLine)
+		}
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no tasks to import")
+	}
+	return rows, nil
+}
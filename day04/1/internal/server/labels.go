@@ -0,0 +1,129 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+func (s *Server) GetLabelsHandler(ctx *gin.Context) {
+	labels, err := s.Db.GetLabels()
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed inquiry")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List labels", "labels": labels})
+}
+
+func (s *Server) AddLabelHandler(ctx *gin.Context) {
+	var label models.Label
+	if err := ctx.ShouldBindJSON(&label); err != nil {
+		s.log.Error().Err(err).Msg("Failed unmarshal body")
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(label); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	labelID, err := s.Db.AddLabel(label)
+	if err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Label successfully added", "label_id": labelID})
+}
+
+func (s *Server) GetLabelByIDHandler(ctx *gin.Context) {
+	label, err := s.Db.GetLabelByID(ctx.Param("id"))
+	if err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Label retrieved", "label": label})
+}
+
+func (s *Server) UpdateLabelHandler(ctx *gin.Context) {
+	var label models.Label
+	if err := ctx.ShouldBindJSON(&label); err != nil {
+		s.log.Error().Err(err).Msg("Failed unmarshal body")
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(label); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	label.ID = ctx.Param("id")
+	if err := s.Db.UpdateLabel(label.ID, label); err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Label updated", "label": label})
+}
+
+func (s *Server) DeleteLabelHandler(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if err := s.Db.DeleteLabel(id); err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Label deleted", "label_id": id})
+}
+
+// GET /tasks/:id/labels
+func (s *Server) GetTaskLabelsHandler(ctx *gin.Context) {
+	labels, err := s.Db.GetTaskLabels(ctx.Param("id"))
+	if err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List task labels", "labels": labels})
+}
+
+// POST /tasks/:id/labels {"label_id": "..."}
+func (s *Server) AttachLabelHandler(ctx *gin.Context) {
+	var req struct {
+		LabelID string `json:"label_id" validate:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		s.log.Error().Err(err).Msg("Failed unmarshal body")
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	taskID := ctx.Param("id")
+	if err := s.Db.AttachLabel(taskID, req.LabelID); err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Label attached", "task_id": taskID, "label_id": req.LabelID})
+}
+
+// DELETE /tasks/:id/labels/:label
+func (s *Server) DetachLabelHandler(ctx *gin.Context) {
+	taskID, labelID := ctx.Param("id"), ctx.Param("label")
+	if err := s.Db.DetachLabel(taskID, labelID); err != nil {
+		s.labelError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Label detached", "task_id": taskID, "label_id": labelID})
+}
+
+func (s *Server) labelError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrTaskNotFound), errors.Is(err, models.ErrLabelNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrLabelExists):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Label name is taken", "error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to change labels")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json" // RFC 7386
+	jsonPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// PATCH /tasks/:id - частичное обновление задачи. Поля, которых нет в патче, не меняются
+func (s *Server) PatchTaskHandler(ctx *gin.Context) {
+	id := ctx.Param("id")
+	current, err := s.Db.GetTaskByID(id)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Not found ID")
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	var task models.Task
+	if status, err := applyPatch(ctx, current, &task); err != nil {
+		s.log.Error().Err(err).Msg("Failed to apply patch")
+		ctx.JSON(status, gin.H{"message": "Patch has not been applied", "error": err.Error()})
+		return
+	}
+	s.saveTask(ctx, current, task)
+}
+
+// Применяет тело запроса как merge patch или JSON patch (по Content-Type)
+// к JSON-представлению current и раскладывает результат в out.
+// При ошибке возвращает подходящий HTTP-статус
+func applyPatch(ctx *gin.Context, current, out any) (int, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	var patched []byte
+	switch ctx.ContentType() {
+	case mergePatchType:
+		patched, err = jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	case jsonPatchType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		patched, err = patch.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return http.StatusConflict, err
+		}
+		if err != nil {
+			return http.StatusUnprocessableEntity, err
+		}
+	default:
+		return http.StatusUnsupportedMediaType,
+			fmt.Errorf("unsupported content type %q, use %s or %s", ctx.ContentType(), mergePatchType, jsonPatchType)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// Колонка доски с карточками в порядке рангов
+type boardColumn struct {
+	models.BoardColumn
+	Cards []models.Task `json:"cards"`
+}
+
+type moveRequest struct {
+	// Колонка, в которую переносится карточка
+	Column string `json:"column" validate:"required"`
+	// Карточки, между которыми она встаёт (выше и ниже). Без обеих - в конец колонки
+	After  string `json:"after" validate:"omitempty,uuid"`
+	Before string `json:"before" validate:"omitempty,uuid"`
+}
+
+// GET /projects
+func (s *Server) GetProjectsHandler(ctx *gin.Context) {
+	projects, err := s.Db.GetProjects()
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List projects", "projects": projects})
+}
+
+// POST /projects {"name": "...", "description": "..."}
+func (s *Server) AddProjectHandler(ctx *gin.Context) {
+	var project models.Project
+	if err := ctx.ShouldBindJSON(&project); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(project); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	project, err := s.Db.AddProject(project)
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Project added", "project": project})
+}
+
+// GET /projects/:id
+func (s *Server) GetProjectHandler(ctx *gin.Context) {
+	project, err := s.Db.GetProject(ctx.Param("id"))
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Project retrieved", "project": project})
+}
+
+// PUT /projects/:id
+func (s *Server) UpdateProjectHandler(ctx *gin.Context) {
+	var project models.Project
+	if err := ctx.ShouldBindJSON(&project); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(project); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	project, err := s.Db.UpdateProject(ctx.Param("id"), project)
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Project updated", "project": project})
+}
+
+// DELETE /projects/:id - удаляет проект с досками; задачи остаются без проекта
+func (s *Server) DeleteProjectHandler(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if err := s.Db.DeleteProject(id); err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Project deleted", "project_id": id})
+}
+
+// GET /projects/:id/boards
+func (s *Server) GetBoardsHandler(ctx *gin.Context) {
+	boards, err := s.Db.GetBoards(ctx.Param("id"))
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List boards", "boards": boards})
+}
+
+// POST /projects/:id/boards {"name": "...", "columns": [{"name": "To do", "statuses": ["todo"]}, ...]}
+func (s *Server) AddBoardHandler(ctx *gin.Context) {
+	board, ok := s.bindBoard(ctx)
+	if !ok {
+		return
+	}
+	board.ProjectID = ctx.Param("id")
+	board, err := s.Db.AddBoard(board)
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Board added", "board": board})
+}
+
+// GET /boards/:id - доска с карточками задач проекта, разложенными по колонкам.
+// Задачи со статусами, которых нет ни в одной колонке, на доску не попадают
+func (s *Server) GetBoardHandler(ctx *gin.Context) {
+	board, err := s.Db.GetBoard(ctx.Param("id"))
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	tasks, err := s.Db.GetProjectTasks(board.ProjectID)
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	columns := make([]boardColumn, len(board.Columns))
+	for i, column := range board.Columns {
+		columns[i] = boardColumn{BoardColumn: column, Cards: []models.Task{}}
+	}
+	for _, task := range tasks {
+		if i := columnOf(board, task.Status); i >= 0 {
+			columns[i].Cards = append(columns[i].Cards, task)
+		}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Board retrieved", "board": board, "columns": columns})
+}
+
+// PUT /boards/:id - новое название и колонки
+func (s *Server) UpdateBoardHandler(ctx *gin.Context) {
+	board, ok := s.bindBoard(ctx)
+	if !ok {
+		return
+	}
+	board, err := s.Db.UpdateBoard(ctx.Param("id"), board)
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Board updated", "board": board})
+}
+
+// DELETE /boards/:id
+func (s *Server) DeleteBoardHandler(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if err := s.Db.DeleteBoard(id); err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Board deleted", "board_id": id})
+}
+
+// POST /boards/:id/cards/:task/move {"column": "...", "after": "<id>", "before": "<id>"}
+// Перенос в другую колонку меняет статус задачи на первый статус колонки
+// по обычным правилам переходов
+func (s *Server) MoveCardHandler(ctx *gin.Context) {
+	var req moveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	board, err := s.Db.GetBoard(ctx.Param("id"))
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	task, err := s.Db.GetTaskByID(ctx.Param("task"))
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	if task.ProjectID == nil || *task.ProjectID != board.ProjectID {
+		s.projectError(ctx, fmt.Errorf("%w: task is not on this board", models.ErrInvalidPosition))
+		return
+	}
+	i := slices.IndexFunc(board.Columns, func(c models.BoardColumn) bool { return c.Name == req.Column })
+	if i < 0 {
+		s.projectError(ctx, fmt.Errorf("%w: unknown column %q", models.ErrInvalidPosition, req.Column))
+		return
+	}
+	to := task.Status
+	if statuses := board.Columns[i].Statuses; !slices.Contains(statuses, task.Status) {
+		to = statuses[0]
+		if !canTransition(task.Status, to) {
+			s.transitionConflict(ctx, task.Status, to)
+			return
+		}
+	}
+	moved, err := s.Db.MoveTask(task.ID, models.TaskMove{From: task.Status, To: to, After: req.After, Before: req.Before}, actor(ctx))
+	if err != nil {
+		s.projectError(ctx, err)
+		return
+	}
+	s.emit(webhook.TaskUpdated, moved)
+	ctx.Header("ETag", etag(moved.Version))
+	ctx.JSON(http.StatusOK, gin.H{"message": "Card moved", "task": moved})
+}
+
+// Разбирает и проверяет доску из тела запроса. При ошибке сам отвечает клиенту
+func (s *Server) bindBoard(ctx *gin.Context) (models.Board, bool) {
+	var board models.Board
+	if err := ctx.ShouldBindJSON(&board); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return models.Board{}, false
+	}
+	err := s.Valid.Struct(board)
+	if err == nil {
+		err = validateColumns(board.Columns)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return models.Board{}, false
+	}
+	return board, true
+}
+
+// Имена колонок не повторяются, и статус принадлежит не больше чем одной колонке
+func validateColumns(columns []models.BoardColumn) error {
+	names := make(map[string]bool, len(columns))
+	statuses := make(map[models.TaskStatus]bool)
+	for _, column := range columns {
+		if names[column.Name] {
+			return fmt.Errorf("duplicate column %q", column.Name)
+		}
+		names[column.Name] = true
+		for _, status := range column.Statuses {
+			if statuses[status] {
+				return fmt.Errorf("status %q is in more than one column", status)
+			}
+			statuses[status] = true
+		}
+	}
+	return nil
+}
+
+// Номер колонки со статусом или -1
+func columnOf(board models.Board, status models.TaskStatus) int {
+	return slices.IndexFunc(board.Columns, func(c models.BoardColumn) bool {
+		return slices.Contains(c.Statuses, status)
+	})
+}
+
+func (s *Server) projectError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrProjectNotFound), errors.Is(err, models.ErrBoardNotFound),
+		errors.Is(err, models.ErrTaskNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidPosition):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+	case errors.Is(err, models.ErrStatusConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Task status was changed concurrently, retry", "error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to change projects")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
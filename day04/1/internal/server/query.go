@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Собирает TaskQuery из параметров GET /tasks:
+// limit, offset, cursor, sort, title, description, status и label (можно несколько),
+// label_match=any|all, assignee и reporter (ID пользователя или "me"), project,
+// q - выражение на языке фильтров (см. пакет filter)
+func parseTaskQuery(ctx *gin.Context) (models.TaskQuery, error) {
+	query := models.TaskQuery{
+		Limit:       defaultLimit,
+		Cursor:      ctx.Query("cursor"),
+		Sort:        ctx.Query("sort"),
+		Title:       ctx.Query("title"),
+		Description: ctx.Query("description"),
+		Labels:      ctx.QueryArray("label"),
+		AssigneeID:  ctx.Query("assignee"),
+		ReporterID:  ctx.Query("reporter"),
+		ProjectID:   ctx.Query("project"),
+	}
+	if _, err := uuid.Parse(query.ProjectID); query.ProjectID != "" && err != nil {
+		return models.TaskQuery{}, fmt.Errorf("invalid project id %q", query.ProjectID)
+	}
+	for _, id := range []string{query.AssigneeID, query.ReporterID} {
+		if _, err := uuid.Parse(id); id != "" && id != currentUser && err != nil {
+			return models.TaskQuery{}, fmt.Errorf("invalid user id %q", id)
+		}
+	}
+	if q := ctx.Query("q"); q != "" {
+		expr, err := filter.Parse(q)
+		if err != nil {
+			return models.TaskQuery{}, fmt.Errorf("invalid q: %w", err)
+		}
+		query.Filter = expr
+	}
+	for _, status := range ctx.QueryArray("status") {
+		if _, ok := transitions[models.TaskStatus(status)]; !ok {
+			return models.TaskQuery{}, fmt.Errorf("unknown status %q", status)
+		}
+		query.Status = append(query.Status, models.TaskStatus(status))
+	}
+	switch ctx.DefaultQuery("label_match", "any") {
+	case "any":
+	case "all":
+		query.LabelsMatchAll = true
+	default:
+		return models.TaskQuery{}, fmt.Errorf("label_match must be any or all")
+	}
+	if v := ctx.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return models.TaskQuery{}, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		query.Limit = limit
+	}
+	if v := ctx.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return models.TaskQuery{}, fmt.Errorf("offset must be a non-negative integer")
+		}
+		query.Offset = offset
+	}
+	return query, nil
+}
+
+// Ссылка на следующую страницу: те же параметры, но с курсором вместо offset
+func nextPageLink(ctx *gin.Context, cursor string) string {
+	u := *ctx.Request.URL
+	params := u.Query()
+	params.Del("offset")
+	params.Set("cursor", cursor)
+	u.RawQuery = params.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/recurrence"
+)
+
+const (
+	// Окно GET /tasks/:id/occurrences по умолчанию и наибольшее
+	defaultOccurrencesWindow = 365 * 24 * time.Hour
+	maxOccurrencesWindow     = 5 * defaultOccurrencesWindow
+)
+
+// Правило повторения должно разбираться, а отсчитывается оно от срока выполнения
+func validateRecurrence(task models.Task) error {
+	if task.Recurrence == "" {
+		return nil
+	}
+	if task.DueAt == nil {
+		return errors.New("recurrence requires due_at")
+	}
+	_, err := recurrence.Parse(task.Recurrence)
+	return err
+}
+
+// GET /tasks/:id/occurrences?from=&to=&limit=N - даты повторений задачи в интервале [from, to].
+// from и to в RFC 3339; по умолчанию - от текущего момента на год вперёд, окно не длиннее 5 лет.
+// Перебор повторений от срока задачи ограничен пакетом recurrence
+func (s *Server) GetOccurrencesHandler(ctx *gin.Context) {
+	from := time.Now().UTC()
+	if v := ctx.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+		from = t
+	}
+	to := from.Add(defaultOccurrencesWindow)
+	if v := ctx.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+		to = t
+	}
+	if to.Before(from) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "to must not be before from"})
+		return
+	}
+	if to.Sub(from) > maxOccurrencesWindow {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "window cannot be longer than 5 years"})
+		return
+	}
+	limit := defaultLimit
+	if v := ctx.Query("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxLimit {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+			return
+		}
+		limit = l
+	}
+
+	task, err := s.Db.GetTaskByID(ctx.Param("id"))
+	if err != nil {
+		s.log.Error().Err(err).Msg("Not found ID")
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if task.Recurrence == "" || task.DueAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Task is not recurring", "error": "task has no recurrence rule"})
+		return
+	}
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		s.log.Error().Err(err).Str("task_id", task.ID).Msg("Stored recurrence rule is invalid")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":     "Task occurrences",
+		"recurrence":  task.Recurrence,
+		"occurrences": rule.Between(*task.DueAt, from, to, limit),
+	})
+}
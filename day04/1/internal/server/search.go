@@ -0,0 +1,36 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /tasks/search?q=...&limit=N - поиск по заголовку и описанию,
+// результаты отсортированы по релевантности
+func (s *Server) SearchTasksHandler(ctx *gin.Context) {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "q is required"})
+		return
+	}
+	limit := defaultLimit
+	if v := ctx.Query("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxLimit {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+			return
+		}
+		limit = l
+	}
+	results, err := s.Db.SearchTasks(q, limit)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to search tasks")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Search results", "results": results})
+}
@@ -1,38 +1,167 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/lahnasti/GO_praktikum/internal/blob"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/stream"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
 	"github.com/rs/zerolog"
 )
 
 type Repository interface {
-	GetAllTasks()([]models.Task, error)
-	AddTask(models.Task) (string, error)
+	GetAllTasks(query models.TaskQuery) (models.TaskPage, error)
+	AddTask(task models.Task, actor string) (string, error)
 	GetTaskByID(id string) (models.Task, error)
-	UpdateTask(id string, task models.Task) error
-	DeleteTask(id string) error
+	UpdateTask(id string, task models.Task, actor string) error
+	UpdateTaskStatus(id string, from, to models.TaskStatus, actor string) error
+	DeleteTask(id string, version int64, cascade bool, actor string) error
+	GetSubtree(id string) ([]models.Task, error)
+	// Каждая задача дерева создаётся так же, как в AddTask (проверки родителя и проекта,
+	// ранг, журнал), но все - в одной транзакции, которую AddTask открыл бы на каждую
+	AddTaskTree(roots []*models.TaskNode, actor string) ([]string, error)
+	SearchTasks(q string, limit int) ([]models.SearchResult, error)
+	GetTrash() ([]models.Task, error)
+	RestoreTask(id, actor string) (models.Task, error)
+	GetHistory(id string) ([]models.AuditRecord, error)
+	GetCalendarTasks(userID string) ([]models.Task, error)
+	CalendarVersion(userID string) (string, error)
+	ImportTasks(tasks []models.Task, actor string) ([]string, error)
+	ExportTasks(ctx context.Context, query models.TaskQuery, fn func(models.Task) error) error
+
+	AddDependency(taskID, dependsOnID string) error
+	RemoveDependency(taskID, dependsOnID string) error
+	GetDependencies(taskID string) ([]models.Task, error)
+	GetActionableTasks() ([]models.Task, error)
+
+	GetLabels() ([]models.Label, error)
+	AddLabel(label models.Label) (string, error)
+	GetLabelByID(id string) (models.Label, error)
+	UpdateLabel(id string, label models.Label) error
+	DeleteLabel(id string) error
+	AttachLabel(taskID, labelID string) error
+	DetachLabel(taskID, labelID string) error
+	GetTaskLabels(taskID string) ([]models.Label, error)
+
+	AddAttachment(att models.Attachment) (models.Attachment, error)
+	GetAttachments(taskID string) ([]models.Attachment, error)
+	GetAttachment(taskID, id string) (models.Attachment, error)
+	DeleteAttachment(taskID, id string) (models.Attachment, error)
+
+	GetProjects() ([]models.Project, error)
+	AddProject(project models.Project) (models.Project, error)
+	GetProject(id string) (models.Project, error)
+	UpdateProject(id string, project models.Project) (models.Project, error)
+	DeleteProject(id string) error
+	GetBoards(projectID string) ([]models.Board, error)
+	AddBoard(board models.Board) (models.Board, error)
+	GetBoard(id string) (models.Board, error)
+	UpdateBoard(id string, board models.Board) (models.Board, error)
+	DeleteBoard(id string) error
+	GetProjectTasks(projectID string) ([]models.Task, error)
+	MoveTask(id string, move models.TaskMove, actor string) (models.Task, error)
+
+	GetTaskStats(projectID string) (models.TaskStats, error)
+	GetTaskFlow(query models.FlowQuery) ([]models.FlowPoint, error)
+}
+
+// Хранилище комментариев к задачам
+type CommentRepository interface {
+	AddComment(comment models.Comment) (models.Comment, error)
+	GetComments(taskID string) ([]models.Comment, error)
+	GetCommentByID(id string) (models.Comment, error)
+	UpdateComment(id, body string) (models.Comment, error)
+	DeleteComment(id string) error
 }
 
 type Server struct {
-	Db Repository
+	Db       Repository
+	Comments CommentRepository
+	// Учёт времени по задачам
+	TimeEntries TimeRepository
+	// Шаблоны задач
+	Templates TemplateRepository
+	// Сохранённые представления списка задач
+	Views ViewRepository
 	Valid *validator.Validate
-	log *zerolog.Logger
+	// Хранилище содержимого вложений и предельный размер одного файла
+	Blobs             blob.Store
+	MaxAttachmentSize int64
+	// Ключ подписи токенов календаря и срок их действия. Пустой ключ - лента выключена
+	CalendarSecret   []byte
+	CalendarTokenTTL time.Duration
+	// Подписки на события и рассылка событий. Events пустой - события не отправляются
+	Webhooks WebhookRepository
+	Events   EventEmitter
+	// Поток изменений задач для SSE и WebSocket. Пустой - поток отключён
+	Stream *stream.Broker
+	// Сервис пользователей: исполнители и авторы задач. Пустой - ID не проверяются
+	Users UserDirectory
+	// Удалять подзадачи вместе с родителем (иначе они переходят к его родителю)
+	CascadeDelete bool
+	log           *zerolog.Logger
 }
 
+func New(db Repository, valid *validator.Validate, zlog *zerolog.Logger) *Server {
+	return &Server{
+		Db:    db,
+		Valid: valid,
+		log:   zlog,
+	}
+}
 
 func (s *Server) GetTasksHandler(ctx *gin.Context) {
-	tasks, err := s.Db.GetAllTasks()
+	query, err := parseTaskQuery(ctx)
 	if err != nil {
+		s.log.Error().Err(err).Msg("Invalid query params")
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	var view models.View
+	if ctx.Query("view") != "" {
+		var ok bool
+		if view, ok = s.applyView(ctx, &query); !ok {
+			return
+		}
+	}
+	if !s.resolveQueryUsers(ctx, &query) {
+		return
+	}
+	page, err := s.Db.GetAllTasks(query)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidQuery) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+			return
+		}
 		s.log.Error().Err(err).Msg("Failed inquiry")
-		ctx.JSON(http.StatusInternalServerError,  gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"message": "List tasks", "users": tasks})
-
+	resp := gin.H{"message": "List tasks", "tasks": page.Tasks, "total": page.Total}
+	if view.ID != "" {
+		resp["view"] = view
+		if len(view.Columns) > 0 {
+			tasks, err := projectTasks(page.Tasks, view.Columns)
+			if err != nil {
+				s.log.Error().Err(err).Msg("Failed to apply view columns")
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			resp["tasks"] = tasks
+		}
+	}
+	if page.NextCursor != "" {
+		next := nextPageLink(ctx, page.NextCursor)
+		ctx.Header("Link", "<"+next+">; rel=\"next\"")
+		resp["next"] = next
+	}
+	ctx.JSON(http.StatusOK, resp)
 }
 
 func (s *Server) AddTaskHandler(ctx *gin.Context) {
@@ -45,23 +174,51 @@ func (s *Server) AddTaskHandler(ctx *gin.Context) {
 	}
 	s.log.Debug().Any("task", task).Msg("Check task from body")
 
+	// Новая задача всегда начинает с todo
+	if task.Status != "" && task.Status != models.StatusTodo {
+		s.transitionConflict(ctx, models.StatusTodo, task.Status)
+		return
+	}
+	task.Status = models.StatusTodo
+
 	err = s.Valid.Struct(task)
+	if err == nil {
+		err = validateDates(task)
+	}
+	if err == nil {
+		err = validateRecurrence(task)
+	}
 	if err != nil {
 		s.log.Error().Err(err).Msg("Failed validation")
 		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
 		return
 	}
-	taskID, err := s.Db.AddTask(task)
+	// Автор по умолчанию - текущий пользователь, если он известен сервису пользователей
+	if task.ReporterID == nil {
+		if user, err := s.me(ctx); err == nil {
+			task.ReporterID = &user.ID
+		}
+	}
+	if err := s.checkUsers(task, nil); err != nil {
+		s.userError(ctx, err, false)
+		return
+	}
+	taskID, err := s.Db.AddTask(task, actor(ctx))
 
+	if errors.Is(err, models.ErrInvalidParent) || errors.Is(err, models.ErrInvalidProject) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
 	if err != nil {
 		s.log.Error().Err(err).Msg("Failed to save task")
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to save task"})
 		return
 	}
+	s.emitTask(webhook.TaskCreated, taskID)
 	ctx.JSON(200, gin.H{"message": "Task successfully added", "task_id": taskID})
 }
 
-func (s *Server) GetTaskByIDHandler (ctx *gin.Context) {
+func (s *Server) GetTaskByIDHandler(ctx *gin.Context) {
 	id := ctx.Param("id")
 	task, err := s.Db.GetTaskByID(id)
 	if err != nil {
@@ -69,10 +226,15 @@ func (s *Server) GetTaskByIDHandler (ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.Header("ETag", etag(task.Version))
+	if match := ctx.GetHeader("If-None-Match"); match != "" && matchETag(match, task.Version, true) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
 	ctx.JSON(200, gin.H{"message": "Task retrieved", "task": task})
 }
 
-func (s *Server) UpdateTaskHandler (ctx *gin.Context) {
+func (s *Server) UpdateTaskHandler(ctx *gin.Context) {
 	var task models.Task
 	if err := ctx.ShouldBindJSON(&task); err != nil {
 		s.log.Error().Err(err).Msg("Failed unmarshal body")
@@ -80,25 +242,129 @@ func (s *Server) UpdateTaskHandler (ctx *gin.Context) {
 		return
 	}
 	id := ctx.Param("id")
-	task.ID = id
-	err := s.Db.UpdateTask(id, task)
+	current, err := s.Db.GetTaskByID(id)
 	if err != nil {
 		s.log.Error().Err(err).Msg("Not found ID")
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	s.saveTask(ctx, current, task)
+}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Task updated", "task": task})
+// Проверяет и сохраняет новую версию задачи current (общая часть PUT и PATCH).
+// Запись проходит, только если задача не изменилась с момента чтения current
+func (s *Server) saveTask(ctx *gin.Context, current, task models.Task) {
+	version, ok := checkIfMatch(ctx, current.Version)
+	if !ok {
+		return
+	}
+	task.ID = current.ID
+	task.CreatedAt = current.CreatedAt
+	task.Version = version
+	task.SeriesID = current.SeriesID
+	task.DeletedAt = nil
+	// Автор задачи не сбрасывается, если его нет в теле PUT
+	if task.ReporterID == nil {
+		task.ReporterID = current.ReporterID
+	}
+	// Ранг выставляет хранилище; при смене проекта он станет известен после записи
+	task.Rank = current.Rank
+	if err := s.Valid.Struct(task); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	if err := validateDates(task); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	if err := validateRecurrence(task); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	// Статус через PUT и PATCH меняется по тем же правилам, что и через /transitions
+	if task.Status == "" {
+		task.Status = current.Status
+	} else if task.Status != current.Status && !canTransition(current.Status, task.Status) {
+		s.transitionConflict(ctx, current.Status, task.Status)
+		return
+	}
+	if err := s.checkUsers(task, nil); err != nil {
+		s.userError(ctx, err, false)
+		return
+	}
+	err := s.Db.UpdateTask(task.ID, task, actor(ctx))
+	if errors.Is(err, models.ErrInvalidParent) || errors.Is(err, models.ErrInvalidProject) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrVersionMismatch) {
+		versionConflict(ctx)
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Not found ID")
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 
+	task.Version++
+	if !equalID(current.ProjectID, task.ProjectID) {
+		if saved, err := s.Db.GetTaskByID(task.ID); err == nil {
+			task = saved
+		}
+	}
+	s.emit(webhook.TaskUpdated, task)
+	s.emitAssigned(current, task, actor(ctx))
+	ctx.Header("ETag", etag(task.Version))
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task updated", "task": task})
 }
 
+// DELETE /tasks/:id?children=cascade|reparent - что делать с подзадачами.
+// Без параметра используется значение по умолчанию из конфигурации
 func (s *Server) DeleteTaskHandler(ctx *gin.Context) {
 	id := ctx.Param("id")
-	err := s.Db.DeleteTask(id)
+	cascade := s.CascadeDelete
+	switch ctx.Query("children") {
+	case "":
+	case "cascade":
+		cascade = true
+	case "reparent":
+		cascade = false
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "children must be cascade or reparent"})
+		return
+	}
+	version := models.AnyVersion
+	if match := ctx.GetHeader("If-Match"); match != "" {
+		current, err := s.Db.GetTaskByID(id)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Not found ID")
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		var ok bool
+		if version, ok = checkIfMatch(ctx, current.Version); !ok {
+			return
+		}
+	}
+	err := s.Db.DeleteTask(id, version, cascade, actor(ctx))
+	if errors.Is(err, models.ErrVersionMismatch) {
+		versionConflict(ctx)
+		return
+	}
 	if err != nil {
 		s.log.Error().Err(err).Msg("Not found ID")
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(200, gin.H{"message": "Task deleted", "task_id": id})
-}
\ No newline at end of file
+	s.emit(webhook.TaskDeleted, gin.H{"id": id, "cascade": cascade})
+	ctx.JSON(200, gin.H{"message": "Task moved to trash", "task_id": id})
+}
+
+// Напоминание не может быть позже срока выполнения
+func validateDates(task models.Task) error {
+	if task.DueAt != nil && task.RemindAt != nil && task.RemindAt.After(*task.DueAt) {
+		return errors.New("remind_at must not be after due_at")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+const (
+	// Период графиков по умолчанию: последние 30 дней, включая сегодня
+	defaultFlowDays = 30
+	// Больше дней в одном графике не бывает
+	maxFlowDays = 366
+)
+
+// Точка диаграммы сгорания: объём работы (задачи кроме отменённых), сделанные
+// и оставшиеся задачи, идеальная линия от остатка в первый день с задачами до нуля в последний
+type burndownPoint struct {
+	Date      string  `json:"date"`
+	Scope     int     `json:"scope"`
+	Completed int     `json:"completed"`
+	Remaining int     `json:"remaining"`
+	Ideal     float64 `json:"ideal"`
+}
+
+// GET /stats/tasks?project= - число задач вне корзины по статусам, исполнителям и меткам
+func (s *Server) TaskStatsHandler(ctx *gin.Context) {
+	projectID, ok := s.statsProject(ctx)
+	if !ok {
+		return
+	}
+	stats, err := s.Db.GetTaskStats(projectID)
+	if err != nil {
+		s.statsError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task stats", "project_id": projectID, "stats": stats})
+}
+
+// GET /stats/cfd?project=&from=&to= - накопительная диаграмма потока: число задач
+// в каждом статусе на конец каждого дня с from по to включительно (даты UTC,
+// по умолчанию последние 30 дней). Считается по журналу изменений задач
+func (s *Server) CumulativeFlowHandler(ctx *gin.Context) {
+	query, ok := s.parseFlowQuery(ctx)
+	if !ok {
+		return
+	}
+	points, err := s.Db.GetTaskFlow(query)
+	if err != nil {
+		s.statsError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":    "Cumulative flow",
+		"project_id": query.ProjectID,
+		"statuses":   models.Statuses,
+		"points":     points,
+	})
+}
+
+// GET /stats/burndown?project=&from=&to= - диаграмма сгорания по тем же дням,
+// что и /stats/cfd
+func (s *Server) BurndownHandler(ctx *gin.Context) {
+	query, ok := s.parseFlowQuery(ctx)
+	if !ok {
+		return
+	}
+	flow, err := s.Db.GetTaskFlow(query)
+	if err != nil {
+		s.statsError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Burndown", "project_id": query.ProjectID, "points": burndown(flow)})
+}
+
+func burndown(flow []models.FlowPoint) []burndownPoint {
+	points := make([]burndownPoint, len(flow))
+	for i, p := range flow {
+		points[i].Date = p.Date
+		for status, n := range p.Counts {
+			if status == models.StatusCancelled {
+				continue
+			}
+			points[i].Scope += n
+			if status.Closed() {
+				points[i].Completed += n
+			}
+		}
+		points[i].Remaining = points[i].Scope - points[i].Completed
+	}
+	// Идеальная линия начинается с первого дня, когда в проекте есть задачи
+	first := 0
+	for first < len(points)-1 && points[first].Scope == 0 {
+		first++
+	}
+	last := len(points) - 1
+	for i := first; i <= last; i++ {
+		points[i].Ideal = float64(points[first].Remaining)
+		if last > first {
+			points[i].Ideal *= float64(last-i) / float64(last-first)
+		}
+	}
+	return points
+}
+
+// Проект из ?project=: пусто или ID существующего проекта. При ошибке сам отвечает клиенту
+func (s *Server) statsProject(ctx *gin.Context) (string, bool) {
+	projectID := ctx.Query("project")
+	if projectID == "" {
+		return "", true
+	}
+	if _, err := uuid.Parse(projectID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": fmt.Sprintf("invalid project id %q", projectID)})
+		return "", false
+	}
+	if _, err := s.Db.GetProject(projectID); err != nil {
+		s.statsError(ctx, err)
+		return "", false
+	}
+	return projectID, true
+}
+
+func (s *Server) parseFlowQuery(ctx *gin.Context) (models.FlowQuery, bool) {
+	projectID, ok := s.statsProject(ctx)
+	if !ok {
+		return models.FlowQuery{}, false
+	}
+	query, err := flowDays(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return models.FlowQuery{}, false
+	}
+	query.ProjectID = projectID
+	return query, true
+}
+
+// Дни графика: даты from и to включительно, по умолчанию последние 30 дней
+func flowDays(from, to string) (models.FlowQuery, error) {
+	var query models.FlowQuery
+	query.To = time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		t, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			return query, errors.New("to must be a date (YYYY-MM-DD)")
+		}
+		query.To = t
+	}
+	query.From = query.To.AddDate(0, 0, 1-defaultFlowDays)
+	if from != "" {
+		t, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return query, errors.New("from must be a date (YYYY-MM-DD)")
+		}
+		query.From = t
+	}
+	if query.From.After(query.To) {
+		return query, errors.New("from must not be after to")
+	}
+	if days := int(query.To.Sub(query.From)/(24*time.Hour)) + 1; days > maxFlowDays {
+		return query, fmt.Errorf("period cannot be longer than %d days", maxFlowDays)
+	}
+	return query, nil
+}
+
+func (s *Server) statsError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrProjectNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to compute stats")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
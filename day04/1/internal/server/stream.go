@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/stream"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+const (
+	// Пустое сообщение, чтобы прокси не закрывали простаивающее соединение
+	streamHeartbeat = 15 * time.Second
+	// Сколько ждать записи в WebSocket
+	streamWriteTimeout = 10 * time.Second
+	// Через сколько браузер переподключается к SSE
+	streamRetry = 3 * time.Second
+)
+
+// Событие reset: часть событий после Last-Event-ID потеряна, задачи нужно перечитать
+const streamReset = "reset"
+
+var upgrader = websocket.Upgrader{}
+
+// Фильтры потока: type, task_id, status (каждый можно несколько раз)
+// и номер последнего полученного события из заголовка Last-Event-ID
+// или параметра last_event_id
+func parseStreamParams(ctx *gin.Context) (stream.Filter, uint64, error) {
+	filter := stream.Filter{
+		Types:   ctx.QueryArray("type"),
+		TaskIDs: ctx.QueryArray("task_id"),
+	}
+	for _, event := range filter.Types {
+		if event == webhook.AllEvents || !webhook.Events[event] {
+			return stream.Filter{}, 0, fmt.Errorf("unknown event %q", event)
+		}
+	}
+	for _, status := range ctx.QueryArray("status") {
+		if _, ok := transitions[models.TaskStatus(status)]; !ok {
+			return stream.Filter{}, 0, fmt.Errorf("unknown status %q", status)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	last := ctx.GetHeader("Last-Event-ID")
+	if last == "" {
+		last = ctx.Query("last_event_id")
+	}
+	var lastID uint64
+	if last != "" {
+		var err error
+		if lastID, err = strconv.ParseUint(last, 10, 64); err != nil {
+			return stream.Filter{}, 0, fmt.Errorf("invalid last event id %q", last)
+		}
+	}
+	return filter, lastID, nil
+}
+
+// Подписывает на поток. При ошибке сам отвечает клиенту
+func (s *Server) subscribe(ctx *gin.Context) (*stream.Subscription, []stream.Event, bool, bool) {
+	if s.Stream == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "task stream is disabled"})
+		return nil, nil, false, false
+	}
+	filter, lastID, err := parseStreamParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return nil, nil, false, false
+	}
+	sub, replay, complete := s.Stream.Hub.Subscribe(lastID, filter)
+	return sub, replay, complete, true
+}
+
+// GET /tasks/stream - изменения задач в формате Server-Sent Events.
+// Поток закрывается, если клиент не успевает читать; EventSource переподключится
+// с Last-Event-ID и получит пропущенное из буфера. При включённой авторизации
+// токен можно передать в ?access_token=
+func (s *Server) StreamTasksHandler(ctx *gin.Context) {
+	sub, replay, complete, ok := s.subscribe(ctx)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	if !complete {
+		fmt.Fprintf(ctx.Writer, "event: %s\ndata: {}\n\n", streamReset)
+	}
+	for _, e := range replay {
+		writeSSE(ctx, e)
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case e, open := <-sub.Events():
+			if !open {
+				return
+			}
+			writeSSE(ctx, e)
+		case <-heartbeat.C:
+			fmt.Fprint(ctx.Writer, ": ping\n\n")
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func writeSSE(ctx *gin.Context, e stream.Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+// GET /tasks/ws - тот же поток через WebSocket: каждое событие - JSON-сообщение,
+// при потере событий первым приходит {"type": "reset"}
+func (s *Server) TasksWebSocketHandler(ctx *gin.Context) {
+	sub, replay, complete, ok := s.subscribe(ctx)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту
+		s.log.Error().Err(err).Msg("Failed to upgrade to WebSocket")
+		return
+	}
+	defer conn.Close()
+
+	// Сообщения клиента не нужны, но читать их надо, чтобы получить pong и закрытие
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(v any) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(v)
+	}
+	if !complete {
+		if err := send(gin.H{"type": streamReset}); err != nil {
+			return
+		}
+	}
+	for _, e := range replay {
+		if err := send(e); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case e, open := <-sub.Events():
+			if !open {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(streamWriteTimeout))
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
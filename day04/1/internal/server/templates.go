@@ -0,0 +1,277 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/placeholder"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// Больше задач в одном шаблоне не бывает
+const maxTemplateTasks = 500
+
+// Хранилище шаблонов задач
+type TemplateRepository interface {
+	GetTemplates() ([]models.Template, error)
+	AddTemplate(template models.Template) (models.Template, error)
+	GetTemplate(id string) (models.Template, error)
+	UpdateTemplate(id string, template models.Template) (models.Template, error)
+	DeleteTemplate(id string) error
+}
+
+// Шаблон из существующей задачи с подзадачами
+type saveTemplateRequest struct {
+	Name        string `json:"name" validate:"required,max=200"`
+	Description string `json:"description"`
+}
+
+type instantiateRequest struct {
+	// Значения подстановок {{name}}
+	Values map[string]string `json:"values"`
+	// Родитель для корневых задач шаблона, проект и исполнитель для всех созданных задач
+	ParentID   *string `json:"parent_id" validate:"omitempty,uuid"`
+	ProjectID  *string `json:"project_id" validate:"omitempty,uuid"`
+	AssigneeID *string `json:"assignee_id" validate:"omitempty,uuid"`
+}
+
+// GET /templates
+func (s *Server) GetTemplatesHandler(ctx *gin.Context) {
+	templates, err := s.Templates.GetTemplates()
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List templates", "templates": templates})
+}
+
+// POST /templates {"name": "...", "tasks": [{"title": "...", "description": "...", "subtasks": [...]}]}
+func (s *Server) AddTemplateHandler(ctx *gin.Context) {
+	template, ok := s.bindTemplate(ctx)
+	if !ok {
+		return
+	}
+	template, err := s.Templates.AddTemplate(template)
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Template added", "template": template})
+}
+
+// GET /templates/:id
+func (s *Server) GetTemplateHandler(ctx *gin.Context) {
+	template, err := s.Templates.GetTemplate(ctx.Param("id"))
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Template retrieved", "template": template})
+}
+
+// PUT /templates/:id
+func (s *Server) UpdateTemplateHandler(ctx *gin.Context) {
+	template, ok := s.bindTemplate(ctx)
+	if !ok {
+		return
+	}
+	template, err := s.Templates.UpdateTemplate(ctx.Param("id"), template)
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Template updated", "template": template})
+}
+
+// DELETE /templates/:id - созданные по шаблону задачи остаются
+func (s *Server) DeleteTemplateHandler(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if err := s.Templates.DeleteTemplate(id); err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Template deleted", "template_id": id})
+}
+
+// POST /tasks/:id/template {"name": "...", "description": "..."} - сохраняет задачу
+// с подзадачами (без корзины) как шаблон. Названия и описания копируются как есть
+func (s *Server) SaveTaskTemplateHandler(ctx *gin.Context) {
+	var req saveTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	tasks, err := s.Db.GetSubtree(ctx.Param("id"))
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	if len(tasks) > maxTemplateTasks {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated",
+			"error": fmt.Sprintf("template cannot have more than %d tasks", maxTemplateTasks)})
+		return
+	}
+	root := buildTree(tasks, -1)
+	template := models.Template{
+		Name:        req.Name,
+		Description: req.Description,
+		Tasks:       templateTasks([]*models.TaskNode{root}),
+	}
+	template.Variables = templateVariables(template.Tasks)
+	template, err = s.Templates.AddTemplate(template)
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Template added", "template": template})
+}
+
+// POST /templates/:id/instantiate {"values": {"name": "..."}, "parent_id": "...", "project_id": "...", "assignee_id": "..."}
+// - создаёт задачи шаблона с заполненными подстановками: все или ни одной.
+// Задачи проверяются и создаются как в POST /tasks, и о каждой уходит task.created
+func (s *Server) InstantiateTemplateHandler(ctx *gin.Context) {
+	var req instantiateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	template, err := s.Templates.GetTemplate(ctx.Param("id"))
+	if err != nil {
+		s.templateError(ctx, err)
+		return
+	}
+	if missing := placeholder.Missing(template.Variables, req.Values); len(missing) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated",
+			"error": "missing values for " + strings.Join(missing, ", ")})
+		return
+	}
+	base := models.Task{
+		Status:     models.StatusTodo,
+		ProjectID:  req.ProjectID,
+		AssigneeID: req.AssigneeID,
+	}
+	if user, err := s.me(ctx); err == nil {
+		base.ReporterID = &user.ID
+	}
+	if err := s.checkUsers(base, nil); err != nil {
+		s.userError(ctx, err, false)
+		return
+	}
+	roots := make([]*models.TaskNode, len(template.Tasks))
+	for i, task := range template.Tasks {
+		roots[i], err = s.fillTemplate(task, base, req.Values)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+			return
+		}
+		roots[i].ParentID = req.ParentID
+	}
+	ids, err := s.Db.AddTaskTree(roots, actor(ctx))
+	if errors.Is(err, models.ErrInvalidParent) || errors.Is(err, models.ErrInvalidProject) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to instantiate template")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to save tasks"})
+		return
+	}
+	for _, id := range ids {
+		s.emitTask(webhook.TaskCreated, id)
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Template instantiated", "task_ids": ids})
+}
+
+// Разбирает и проверяет шаблон из тела запроса, выставляет имена подстановок.
+// При ошибке сам отвечает клиенту
+func (s *Server) bindTemplate(ctx *gin.Context) (models.Template, bool) {
+	var template models.Template
+	if err := ctx.ShouldBindJSON(&template); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return models.Template{}, false
+	}
+	err := s.Valid.Struct(template)
+	if err == nil && countTemplateTasks(template.Tasks) > maxTemplateTasks {
+		err = fmt.Errorf("template cannot have more than %d tasks", maxTemplateTasks)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return models.Template{}, false
+	}
+	template.Variables = templateVariables(template.Tasks)
+	return template, true
+}
+
+// Узел дерева задач из задачи шаблона: base с подставленными названием и описанием
+func (s *Server) fillTemplate(task models.TemplateTask, base models.Task, values map[string]string) (*models.TaskNode, error) {
+	node := &models.TaskNode{Task: base}
+	node.Title = placeholder.Fill(task.Title, values)
+	node.Description = placeholder.Fill(task.Description, values)
+	if err := s.Valid.Struct(node.Task); err != nil {
+		return nil, fmt.Errorf("task %q: %w", task.Title, err)
+	}
+	for _, subtask := range task.Subtasks {
+		child, err := s.fillTemplate(subtask, base, values)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
+
+func templateTasks(nodes []*models.TaskNode) []models.TemplateTask {
+	var tasks []models.TemplateTask
+	for _, node := range nodes {
+		tasks = append(tasks, models.TemplateTask{
+			Title:       node.Title,
+			Description: node.Description,
+			Subtasks:    templateTasks(node.Children),
+		})
+	}
+	return tasks
+}
+
+func countTemplateTasks(tasks []models.TemplateTask) int {
+	n := len(tasks)
+	for _, task := range tasks {
+		n += countTemplateTasks(task.Subtasks)
+	}
+	return n
+}
+
+// Имена подстановок из названий и описаний всех задач шаблона
+func templateVariables(tasks []models.TemplateTask) []string {
+	var texts []string
+	var collect func(tasks []models.TemplateTask)
+	collect = func(tasks []models.TemplateTask) {
+		for _, task := range tasks {
+			texts = append(texts, task.Title, task.Description)
+			collect(task.Subtasks)
+		}
+	}
+	collect(tasks)
+	return placeholder.Names(texts...)
+}
+
+func (s *Server) templateError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrTemplateNotFound), errors.Is(err, models.ErrTaskNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to change templates")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
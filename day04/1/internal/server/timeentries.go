@@ -0,0 +1,302 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Период отчёта по умолчанию: последние 7 дней
+const defaultTimesheetWindow = 7 * 24 * time.Hour
+
+// Хранилище учёта времени по задачам
+type TimeRepository interface {
+	StartTimer(taskID, user string) (models.TimeEntry, error)
+	StopTimer(taskID, user string) (models.TimeEntry, error)
+	GetRunningTimer(user string) (models.TimeEntry, error)
+	AddTimeEntry(entry models.TimeEntry) (models.TimeEntry, error)
+	GetTimeEntries(taskID string) ([]models.TimeEntry, error)
+	GetTimeEntry(id string) (models.TimeEntry, error)
+	DeleteTimeEntry(id string) error
+	GetTimesheet(query models.TimesheetQuery) ([]models.TimesheetRow, error)
+}
+
+// Запись, внесённая вручную: начало и конец либо начало и длительность ("1h30m")
+type timeEntryRequest struct {
+	StartedAt time.Time  `json:"started_at" validate:"required"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Duration  string     `json:"duration"`
+	Note      string     `json:"note" validate:"max=1000"`
+}
+
+// POST /tasks/:id/timer/start - запускает таймер текущего пользователя по задаче.
+// Если у него уже идёт таймер, отвечает 409 с этим таймером
+func (s *Server) StartTimerHandler(ctx *gin.Context) {
+	user, ok := timeUser(ctx)
+	if !ok {
+		return
+	}
+	task, ok := s.commentedTask(ctx)
+	if !ok {
+		return
+	}
+	entry, err := s.TimeEntries.StartTimer(task.ID, user)
+	if errors.Is(err, models.ErrTimerRunning) {
+		resp := gin.H{"message": "Stop the running timer first", "error": err.Error()}
+		if running, err := s.TimeEntries.GetRunningTimer(user); err == nil {
+			resp["timer"] = running
+		}
+		ctx.JSON(http.StatusConflict, resp)
+		return
+	}
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Timer started", "timer": entry})
+}
+
+// POST /tasks/:id/timer/stop - останавливает таймер текущего пользователя по задаче
+func (s *Server) StopTimerHandler(ctx *gin.Context) {
+	user, ok := timeUser(ctx)
+	if !ok {
+		return
+	}
+	entry, err := s.TimeEntries.StopTimer(ctx.Param("id"), user)
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Timer stopped", "entry": entry})
+}
+
+// GET /timer - идущий таймер текущего пользователя (timer пустой, если таймер не запущен)
+func (s *Server) GetTimerHandler(ctx *gin.Context) {
+	user, ok := timeUser(ctx)
+	if !ok {
+		return
+	}
+	entry, err := s.TimeEntries.GetRunningTimer(user)
+	if errors.Is(err, models.ErrTimeEntryNotFound) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "No running timer", "timer": nil})
+		return
+	}
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Running timer", "timer": entry})
+}
+
+// GET /tasks/:id/time - записи времени по задаче всех пользователей и их сумма
+func (s *Server) GetTimeEntriesHandler(ctx *gin.Context) {
+	task, ok := s.commentedTask(ctx)
+	if !ok {
+		return
+	}
+	entries, err := s.TimeEntries.GetTimeEntries(task.ID)
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.Seconds
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task time entries", "entries": entries, "total_seconds": total})
+}
+
+// POST /tasks/:id/time {"started_at": "...", "ended_at": "..." | "duration": "1h30m", "note": "..."}
+// - запись времени текущего пользователя, внесённая вручную
+func (s *Server) AddTimeEntryHandler(ctx *gin.Context) {
+	user, ok := timeUser(ctx)
+	if !ok {
+		return
+	}
+	var req timeEntryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	err := s.Valid.Struct(req)
+	var endedAt time.Time
+	if err == nil {
+		endedAt, err = entryEndedAt(req)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	task, ok := s.commentedTask(ctx)
+	if !ok {
+		return
+	}
+	entry, err := s.TimeEntries.AddTimeEntry(models.TimeEntry{
+		TaskID:    task.ID,
+		User:      user,
+		StartedAt: req.StartedAt.UTC(),
+		EndedAt:   &endedAt,
+		Note:      req.Note,
+	})
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Time entry added", "entry": entry})
+}
+
+// DELETE /tasks/:id/time/:entry - удаляет свою запись
+func (s *Server) DeleteTimeEntryHandler(ctx *gin.Context) {
+	user, ok := timeUser(ctx)
+	if !ok {
+		return
+	}
+	entry, err := s.TimeEntries.GetTimeEntry(ctx.Param("entry"))
+	if err == nil && entry.TaskID != ctx.Param("id") {
+		err = models.ErrTimeEntryNotFound
+	}
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	if user != entry.User {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can delete the time entry", "error": "forbidden"})
+		return
+	}
+	if err := s.TimeEntries.DeleteTimeEntry(entry.ID); err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Time entry deleted", "entry_id": entry.ID})
+}
+
+// GET /reports/timesheet?from=&to=&group_by=task|user|day&user=&task= - суммы времени
+// по записям, начатым в [from, to). from и to - RFC 3339 или дата (начало суток UTC),
+// по умолчанию последние 7 дней. Запись целиком относится ко дню своего начала,
+// идущие таймеры считаются до текущего момента. user=me - текущий пользователь
+func (s *Server) TimesheetHandler(ctx *gin.Context) {
+	query, err := parseTimesheetQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	rows, err := s.TimeEntries.GetTimesheet(query)
+	if err != nil {
+		s.timeError(ctx, err)
+		return
+	}
+	var total int64
+	for _, row := range rows {
+		total += row.Seconds
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       "Timesheet",
+		"from":          query.From,
+		"to":            query.To,
+		"group_by":      query.GroupBy,
+		"rows":          rows,
+		"total_seconds": total,
+	})
+}
+
+func parseTimesheetQuery(ctx *gin.Context) (models.TimesheetQuery, error) {
+	query := models.TimesheetQuery{
+		To:      time.Now().UTC(),
+		GroupBy: models.TimesheetGroup(ctx.DefaultQuery("group_by", string(models.TimesheetByTask))),
+		User:    ctx.Query("user"),
+		TaskID:  ctx.Query("task"),
+	}
+	switch query.GroupBy {
+	case models.TimesheetByTask, models.TimesheetByUser, models.TimesheetByDay:
+	default:
+		return query, errors.New("group_by must be task, user or day")
+	}
+	if v := ctx.Query("to"); v != "" {
+		t, err := parseReportTime(v)
+		if err != nil {
+			return query, fmt.Errorf("to: %w", err)
+		}
+		query.To = t
+	}
+	query.From = query.To.Add(-defaultTimesheetWindow)
+	if v := ctx.Query("from"); v != "" {
+		t, err := parseReportTime(v)
+		if err != nil {
+			return query, fmt.Errorf("from: %w", err)
+		}
+		query.From = t
+	}
+	if !query.From.Before(query.To) {
+		return query, errors.New("from must be before to")
+	}
+	if query.User == currentUser {
+		query.User = actor(ctx)
+	}
+	if query.TaskID != "" {
+		if _, err := uuid.Parse(query.TaskID); err != nil {
+			return query, errors.New("task must be a UUID")
+		}
+	}
+	return query, nil
+}
+
+// Таймеры и записи времени принадлежат пользователю из JWT. Без авторизации
+// отвечает 401: иначе все анонимные клиенты делили бы один таймер
+func timeUser(ctx *gin.Context) (string, bool) {
+	username := ctx.GetString(usernameKey)
+	if username == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"message": "Authorization required", "error": "time tracking needs a signed in user"})
+		return "", false
+	}
+	return username, true
+}
+
+// Момент в RFC 3339 или дата (начало суток UTC)
+func parseReportTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC 3339 timestamp or a date (YYYY-MM-DD)")
+	}
+	return t, nil
+}
+
+// Конец записи, внесённой вручную: ended_at или started_at + duration
+func entryEndedAt(req timeEntryRequest) (time.Time, error) {
+	if (req.EndedAt == nil) == (req.Duration == "") {
+		return time.Time{}, errors.New("either ended_at or duration is required")
+	}
+	var end time.Time
+	if req.EndedAt != nil {
+		end = *req.EndedAt
+	} else {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid duration: %w", err)
+		}
+		end = req.StartedAt.Add(d)
+	}
+	if !end.After(req.StartedAt) {
+		return time.Time{}, errors.New("time entry must end after it starts")
+	}
+	return end.UTC(), nil
+}
+
+func (s *Server) timeError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrTaskNotFound), errors.Is(err, models.ErrTimeEntryNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrTimerRunning), errors.Is(err, models.ErrTimerNotRunning):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to track time")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// Таблица допустимых переходов между статусами задачи.
+// done и cancelled - конечные статусы
+var transitions = map[models.TaskStatus][]models.TaskStatus{
+	models.StatusTodo:       {models.StatusInProgress, models.StatusCancelled},
+	models.StatusInProgress: {models.StatusTodo, models.StatusReview, models.StatusCancelled},
+	models.StatusReview:     {models.StatusInProgress, models.StatusDone, models.StatusCancelled},
+	models.StatusDone:       {},
+	models.StatusCancelled:  {},
+}
+
+func canTransition(from, to models.TaskStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type transitionRequest struct {
+	To models.TaskStatus `json:"to" validate:"required,oneof=todo in_progress review done cancelled"`
+}
+
+// POST /tasks/:id/transitions - перевод задачи в другой статус
+func (s *Server) TransitionTaskHandler(ctx *gin.Context) {
+	var req transitionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		s.log.Error().Err(err).Msg("Failed unmarshal body")
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if err := s.Valid.Struct(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+
+	id := ctx.Param("id")
+	task, err := s.Db.GetTaskByID(id)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Not found ID")
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !canTransition(task.Status, req.To) {
+		s.transitionConflict(ctx, task.Status, req.To)
+		return
+	}
+	if err := s.Db.UpdateTaskStatus(id, task.Status, req.To, actor(ctx)); err != nil {
+		if errors.Is(err, models.ErrStatusConflict) {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Task status was changed concurrently, retry", "error": err.Error()})
+			return
+		}
+		s.log.Error().Err(err).Msg("Failed to update task status")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update task status"})
+		return
+	}
+	task.Status = req.To
+	task.Version++
+	s.emit(webhook.TaskUpdated, task)
+	ctx.Header("ETag", etag(task.Version))
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task status changed", "task": task})
+}
+
+func (s *Server) transitionConflict(ctx *gin.Context, from, to models.TaskStatus) {
+	ctx.JSON(http.StatusConflict, gin.H{
+		"message": "Illegal status transition",
+		"error":   "cannot move task from " + string(from) + " to " + string(to),
+		"from":    from,
+		"to":      to,
+		"allowed": transitions[from],
+	})
+}
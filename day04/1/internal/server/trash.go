@@ -0,0 +1,43 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// GET /trash - удалённые задачи, которые ещё можно восстановить
+func (s *Server) GetTrashHandler(ctx *gin.Context) {
+	tasks, err := s.Db.GetTrash()
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to get trash")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Trash", "tasks": tasks})
+}
+
+// POST /tasks/:id/restore - возвращает задачу из корзины
+// вместе с подзадачами, удалёнными вместе с ней
+func (s *Server) RestoreTaskHandler(ctx *gin.Context) {
+	task, err := s.Db.RestoreTask(ctx.Param("id"), actor(ctx))
+	if errors.Is(err, models.ErrTaskNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Task is not in trash", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrParentDeleted) {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Restore the parent task first", "error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to restore task")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.emit(webhook.TaskRestored, task)
+	ctx.Header("ETag", etag(task.Version))
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task restored", "task": task})
+}
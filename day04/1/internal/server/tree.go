@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// GET /tasks/:id/tree?depth=N - задача с вложенными подзадачами.
+// depth ограничивает глубину ответа, но процент выполнения считается по всему поддереву
+func (s *Server) GetTaskTreeHandler(ctx *gin.Context) {
+	depth := -1
+	if v := ctx.Query("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "depth must be a non-negative integer"})
+			return
+		}
+		depth = d
+	}
+	tasks, err := s.Db.GetSubtree(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, models.ErrTaskNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.log.Error().Err(err).Msg("Failed to get task tree")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Task tree", "tree": buildTree(tasks, depth)})
+}
+
+// Собирает дерево из плоского списка, где корень идёт первым,
+// а каждый родитель - раньше своих подзадач
+func buildTree(tasks []models.Task, depth int) *models.TaskNode {
+	nodes := make(map[string]*models.TaskNode, len(tasks))
+	for _, task := range tasks {
+		nodes[task.ID] = &models.TaskNode{Task: task}
+	}
+	root := nodes[tasks[0].ID]
+	for _, task := range tasks[1:] {
+		if parent, ok := nodes[*task.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[task.ID])
+		}
+	}
+	rollup(root)
+	prune(root, depth)
+	return root
+}
+
+// Процент выполнения: у листа 100 или 0, у родителя - среднее по подзадачам.
+// Отменённые подзадачи в расчёт не входят
+func rollup(node *models.TaskNode) float64 {
+	var sum float64
+	var count int
+	for _, child := range node.Children {
+		p := rollup(child)
+		if child.Status != models.StatusCancelled {
+			sum += p
+			count++
+		}
+	}
+	switch {
+	case count > 0:
+		node.Progress = sum / float64(count)
+	case node.Status == models.StatusDone:
+		node.Progress = 100
+	default:
+		node.Progress = 0
+	}
+	return node.Progress
+}
+
+func prune(node *models.TaskNode, depth int) {
+	if depth == 0 {
+		node.Children = nil
+		return
+	}
+	for _, child := range node.Children {
+		prune(child, depth-1)
+	}
+}
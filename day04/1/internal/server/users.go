@@ -0,0 +1,184 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// Вместо ID пользователя в фильтрах: текущий пользователь из JWT
+const currentUser = "me"
+
+var (
+	errNoUserDirectory = errors.New("user service is not configured")
+	errAnonymous       = errors.New("authentication required to resolve \"me\"")
+)
+
+// Сервис пользователей (users.Client)
+type UserDirectory interface {
+	GetUser(id string) (models.User, error)
+	FindUser(name string) (models.User, error)
+}
+
+// Пользователь, от имени которого выполняется запрос
+func (s *Server) me(ctx *gin.Context) (models.User, error) {
+	if s.Users == nil {
+		return models.User{}, errNoUserDirectory
+	}
+	name := ctx.GetString(usernameKey)
+	if name == "" {
+		return models.User{}, errAnonymous
+	}
+	return s.Users.FindUser(name)
+}
+
+// Заменяет "me" на ID текущего пользователя
+func (s *Server) resolveUser(ctx *gin.Context, id string) (string, error) {
+	if id != currentUser {
+		return id, nil
+	}
+	user, err := s.me(ctx)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+// Проверяет, что исполнитель и автор задачи есть в сервисе пользователей.
+// known - уже проверенные ID (для пакетных операций), может быть nil.
+// Без сервиса пользователей ID не проверяются
+func (s *Server) checkUsers(task models.Task, known map[string]bool) error {
+	if s.Users == nil {
+		return nil
+	}
+	for _, id := range []*string{task.AssigneeID, task.ReporterID} {
+		if id == nil || known[*id] {
+			continue
+		}
+		if _, err := s.Users.GetUser(*id); err != nil {
+			return err
+		}
+		if known != nil {
+			known[*id] = true
+		}
+	}
+	return nil
+}
+
+// Отвечает на ошибку поиска пользователя. subject - пользователь из URL
+// (тогда его отсутствие - 404), иначе пользователь из тела запроса (400)
+func (s *Server) userError(ctx *gin.Context, err error, subject bool) {
+	switch {
+	case errors.Is(err, errAnonymous):
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUserNotFound) && subject:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUserNotFound):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+	case errors.Is(err, models.ErrAmbiguousUser):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errNoUserDirectory):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to read user")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+}
+
+// Подставляет ID вместо "me" в фильтрах assignee и reporter, в том числе
+// в условиях выражения q. При ошибке сам отвечает клиенту
+func (s *Server) resolveQueryUsers(ctx *gin.Context, query *models.TaskQuery) bool {
+	ids := []*string{&query.AssigneeID, &query.ReporterID}
+	filter.Walk(query.Filter, func(cond *filter.Cond) error {
+		if cond.Field == "assignee" || cond.Field == "reporter" {
+			ids = append(ids, &cond.Value)
+		}
+		return nil
+	})
+	for _, id := range ids {
+		resolved, err := s.resolveUser(ctx, *id)
+		if err != nil {
+			s.userError(ctx, err, true)
+			return false
+		}
+		*id = resolved
+	}
+	return true
+}
+
+// GET /users/:id/tasks?role=assignee|reporter - задачи пользователя (id или "me"):
+// назначенные ему (по умолчанию) или созданные им. Остальные параметры - как у GET /tasks
+func (s *Server) GetUserTasksHandler(ctx *gin.Context) {
+	query, err := parseTaskQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	if !s.resolveQueryUsers(ctx, &query) {
+		return
+	}
+	id, err := s.resolveUser(ctx, ctx.Param("id"))
+	if err == nil && ctx.Param("id") != currentUser {
+		if _, parseErr := uuid.Parse(id); parseErr != nil {
+			err = models.ErrUserNotFound
+		} else if s.Users != nil {
+			_, err = s.Users.GetUser(id)
+		}
+	}
+	if err != nil {
+		s.userError(ctx, err, true)
+		return
+	}
+	switch ctx.DefaultQuery("role", "assignee") {
+	case "assignee":
+		query.AssigneeID = id
+	case "reporter":
+		query.ReporterID = id
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": "role must be assignee or reporter"})
+		return
+	}
+	page, err := s.Db.GetAllTasks(query)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidQuery) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+			return
+		}
+		s.log.Error().Err(err).Msg("Failed inquiry")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := gin.H{"message": "List user tasks", "user_id": id, "tasks": page.Tasks, "total": page.Total}
+	if page.NextCursor != "" {
+		next := nextPageLink(ctx, page.NextCursor)
+		ctx.Header("Link", "<"+next+">; rel=\"next\"")
+		resp["next"] = next
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// Событие о смене исполнителя задачи
+func (s *Server) emitAssigned(old, task models.Task, actor string) {
+	if equalID(old.AssigneeID, task.AssigneeID) {
+		return
+	}
+	s.emit(webhook.TaskAssigned, gin.H{
+		"id":                   task.ID,
+		"status":               task.Status,
+		"assignee_id":          task.AssigneeID,
+		"previous_assignee_id": old.AssigneeID,
+		"actor":                actor,
+	})
+}
+
+func equalID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
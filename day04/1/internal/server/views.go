@@ -0,0 +1,240 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/filter"
+)
+
+// Хранилище сохранённых представлений списка задач
+type ViewRepository interface {
+	GetViews(user string) ([]models.View, error)
+	AddView(view models.View) (models.View, error)
+	GetView(id string) (models.View, error)
+	UpdateView(id string, view models.View) (models.View, error)
+	DeleteView(id string) error
+}
+
+// GET /views - представления текущего пользователя и открытые ему другими
+func (s *Server) GetViewsHandler(ctx *gin.Context) {
+	username, ok := viewUser(ctx)
+	if !ok {
+		return
+	}
+	views, err := s.Views.GetViews(username)
+	if err != nil {
+		s.viewError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List views", "views": views})
+}
+
+// POST /views {"name": "...", "query": "...", "sort": "...", "columns": [...], "shared_with": [...]}
+func (s *Server) AddViewHandler(ctx *gin.Context) {
+	username, ok := viewUser(ctx)
+	if !ok {
+		return
+	}
+	view, ok := s.bindView(ctx)
+	if !ok {
+		return
+	}
+	view.Owner = username
+	view.SharedWith = slices.DeleteFunc(view.SharedWith, func(user string) bool { return user == username })
+	view, err := s.Views.AddView(view)
+	if err != nil {
+		s.viewError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "View added", "view": view})
+}
+
+// GET /views/:id
+func (s *Server) GetViewHandler(ctx *gin.Context) {
+	view, ok := s.visibleView(ctx, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "View retrieved", "view": view})
+}
+
+// PUT /views/:id - менять представление может только владелец
+func (s *Server) UpdateViewHandler(ctx *gin.Context) {
+	old, ok := s.ownView(ctx)
+	if !ok {
+		return
+	}
+	view, ok := s.bindView(ctx)
+	if !ok {
+		return
+	}
+	view.SharedWith = slices.DeleteFunc(view.SharedWith, func(user string) bool { return user == old.Owner })
+	view, err := s.Views.UpdateView(old.ID, view)
+	if err != nil {
+		s.viewError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "View updated", "view": view})
+}
+
+// DELETE /views/:id - удалять представление может только владелец
+func (s *Server) DeleteViewHandler(ctx *gin.Context) {
+	view, ok := s.ownView(ctx)
+	if !ok {
+		return
+	}
+	if err := s.Views.DeleteView(view.ID); err != nil {
+		s.viewError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "View deleted", "view_id": view.ID})
+}
+
+// Применяет к запросу представление из ?view=<id>: его фильтр добавляется к q
+// через AND, его сортировка действует, если не задан ?sort. При ошибке сам отвечает клиенту,
+// без авторизации - 401 (см. viewUser)
+func (s *Server) applyView(ctx *gin.Context, query *models.TaskQuery) (models.View, bool) {
+	view, ok := s.visibleView(ctx, ctx.Query("view"))
+	if !ok {
+		return models.View{}, false
+	}
+	if view.Query != "" {
+		expr, err := filter.Parse(view.Query)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": fmt.Sprintf("view query: %v", err)})
+			return models.View{}, false
+		}
+		if query.Filter != nil {
+			expr = &filter.And{Left: expr, Right: query.Filter}
+		}
+		query.Filter = expr
+	}
+	if query.Sort == "" {
+		query.Sort = view.Sort
+	}
+	return view, true
+}
+
+// Задачи только с id и колонками представления
+func projectTasks(tasks []models.Task, columns []string) ([]map[string]any, error) {
+	projected := make([]map[string]any, len(tasks))
+	for i, task := range tasks {
+		data, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		projected[i] = map[string]any{"id": task.ID}
+		for _, column := range columns {
+			projected[i][column] = fields[column]
+		}
+	}
+	return projected, nil
+}
+
+// Владелец представлений - пользователь из claim username токена, который выпускает
+// сервис авторизации day04/3 и проверяет общий AuthMiddleware (day04/shared/auth).
+// Без авторизации отвечает 401: с пустым -jwt-secret (по умолчанию) middleware
+// не подключается, и все запросы к /views и ?view= получают 401
+func viewUser(ctx *gin.Context) (string, bool) {
+	username := ctx.GetString(usernameKey)
+	if username == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"message": "Authorization required", "error": "views need a signed in user"})
+		return "", false
+	}
+	return username, true
+}
+
+// Представление, если текущий пользователь - его владелец или оно ему открыто.
+// Чужие закрытые представления не отличаются от несуществующих
+func (s *Server) visibleView(ctx *gin.Context, id string) (models.View, bool) {
+	username, ok := viewUser(ctx)
+	if !ok {
+		return models.View{}, false
+	}
+	view, err := s.Views.GetView(id)
+	if err == nil && view.Owner != username && !slices.Contains(view.SharedWith, username) {
+		err = models.ErrViewNotFound
+	}
+	if err != nil {
+		s.viewError(ctx, err)
+		return models.View{}, false
+	}
+	return view, true
+}
+
+// Представление из :id, если текущий пользователь - его владелец
+func (s *Server) ownView(ctx *gin.Context) (models.View, bool) {
+	view, ok := s.visibleView(ctx, ctx.Param("id"))
+	if !ok {
+		return models.View{}, false
+	}
+	if view.Owner != ctx.GetString(usernameKey) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can change the view", "error": "forbidden"})
+		return models.View{}, false
+	}
+	return view, true
+}
+
+// Разбирает и проверяет представление из тела запроса. При ошибке сам отвечает клиенту
+func (s *Server) bindView(ctx *gin.Context) (models.View, bool) {
+	var view models.View
+	if err := ctx.ShouldBindJSON(&view); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return models.View{}, false
+	}
+	err := s.Valid.Struct(view)
+	if err == nil && view.Query != "" {
+		if _, parseErr := filter.Parse(view.Query); parseErr != nil {
+			err = fmt.Errorf("invalid query: %w", parseErr)
+		}
+	}
+	if err == nil {
+		err = validateViewColumns(view.Columns)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return models.View{}, false
+	}
+	if view.Columns == nil {
+		view.Columns = []string{}
+	}
+	if view.SharedWith == nil {
+		view.SharedWith = []string{}
+	}
+	slices.Sort(view.SharedWith)
+	view.SharedWith = slices.Compact(view.SharedWith)
+	return view, true
+}
+
+// Колонки представления - поля задачи из выгрузки CSV, без повторов
+func validateViewColumns(columns []string) error {
+	for i, column := range columns {
+		if !slices.Contains(csvColumns, column) {
+			return fmt.Errorf("unknown column %q", column)
+		}
+		if slices.Contains(columns[:i], column) {
+			return fmt.Errorf("duplicate column %q", column)
+		}
+	}
+	return nil
+}
+
+func (s *Server) viewError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrViewNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		s.log.Error().Err(err).Msg("Failed to change views")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
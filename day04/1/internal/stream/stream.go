@@ -0,0 +1,256 @@
+// Пакет stream раздаёт события об изменениях задач подключённым клиентам
+// (SSE, WebSocket). Hub хранит последние события для продолжения потока
+// с Last-Event-ID, Broker доставляет события во все экземпляры сервиса
+// через общую шину (Postgres LISTEN/NOTIFY)
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Очередь событий одного подписчика. Кто не успевает её разбирать,
+	// отключается и переподключается с Last-Event-ID
+	subscriberBuffer = 64
+	// Предел размера уведомления Postgres (8000 байт) с запасом
+	maxPayload = 7900
+	// Пауза перед повторной подпиской на шину после ошибки
+	retryDelay = time.Second
+)
+
+type Event struct {
+	// Номер события: растёт с каждым событием, общий для всех экземпляров сервиса
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Состояние задачи. Пустое, если не поместилось в уведомление, - тогда
+	// задачу читают через GET /tasks/:id
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Отбор событий для подписчика. Пустое поле - без ограничения
+type Filter struct {
+	Types    []string
+	TaskIDs  []string
+	Statuses []string
+}
+
+func (f Filter) Match(e Event) bool {
+	return matchAny(f.Types, e.Type) && matchAny(f.TaskIDs, e.TaskID) && matchAny(f.Statuses, e.Status)
+}
+
+func matchAny(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	ch     chan Event
+	once   sync.Once
+}
+
+// События подписки. Канал закрывается, когда подписчик отстал или отписался
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+// Hub раздаёт события подписчикам и помнит последние size событий
+type Hub struct {
+	mu   sync.Mutex
+	last uint64
+	// Кольцевой буфер: ring[next] - самое старое событие, когда буфер заполнен
+	ring []Event
+	next int
+	full bool
+	subs map[*Subscription]struct{}
+}
+
+func NewHub(size int) *Hub {
+	return &Hub{
+		ring: make([]Event, size),
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish раздаёт событие. Событие без номера получает следующий номер этого Hub
+func (h *Hub) Publish(e Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if e.ID == 0 {
+		e.ID = h.last + 1
+	}
+	h.last = max(h.last, e.ID)
+	if len(h.ring) > 0 {
+		h.ring[h.next] = e
+		h.next = (h.next + 1) % len(h.ring)
+		h.full = h.full || h.next == 0
+	}
+	for sub := range h.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			h.drop(sub)
+		}
+	}
+	return e
+}
+
+// Subscribe подписывает на события после lastID (0 - только новые) и возвращает
+// подходящие события из буфера. complete = false, если часть событий после
+// lastID уже вытеснена из буфера и клиенту нужно перечитать задачи
+func (h *Hub) Subscribe(lastID uint64, filter Filter) (sub *Subscription, replay []Event, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	complete = true
+	if lastID > 0 {
+		buffered := h.buffered()
+		// Пропуск есть, если самое старое событие буфера новее следующего после
+		// lastID. Номер новее последнего известного (например, после перезапуска
+		// без шины) тоже требует перечитать задачи
+		switch {
+		case lastID > h.last:
+			complete = false
+		case lastID < h.last:
+			complete = len(buffered) > 0 && buffered[0].ID <= lastID+1
+		}
+		for _, e := range buffered {
+			if e.ID > lastID && filter.Match(e) {
+				replay = append(replay, e)
+			}
+		}
+	}
+	sub = &Subscription{hub: h, filter: filter, ch: make(chan Event, subscriberBuffer)}
+	h.subs[sub] = struct{}{}
+	return sub, replay, complete
+}
+
+// События буфера от старых к новым
+func (h *Hub) buffered() []Event {
+	if !h.full {
+		return h.ring[:h.next]
+	}
+	return append(append([]Event(nil), h.ring[h.next:]...), h.ring[:h.next]...)
+}
+
+// Вызывается под h.mu
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		sub.once.Do(func() { close(sub.ch) })
+	}
+}
+
+// Общая шина событий между экземплярами сервиса
+type Bus interface {
+	// Отправляет событие всем слушателям шины, включая этот экземпляр.
+	// Шина присваивает событию номер
+	PublishEvent(payload []byte) error
+	// Передаёт в fn события шины до отмены ctx или ошибки соединения
+	ListenEvents(ctx context.Context, fn func(payload []byte)) error
+}
+
+// Broker принимает события сервиса и доставляет их в Hub: напрямую или через
+// шину, если сервис запущен в нескольких экземплярах
+type Broker struct {
+	Hub *Hub
+	bus Bus
+	log *zerolog.Logger
+}
+
+// bus = nil - события остаются в этом экземпляре
+func NewBroker(hub *Hub, bus Bus, zlog *zerolog.Logger) *Broker {
+	return &Broker{
+		Hub: hub,
+		bus: bus,
+		log: zlog,
+	}
+}
+
+// Emit публикует событие об изменении задачи. data - задача или объект с её id
+func (b *Broker) Emit(event string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		b.log.Error().Err(err).Str("event", event).Msg("Failed to encode stream event")
+		return
+	}
+	var task struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	_ = json.Unmarshal(raw, &task)
+	e := Event{
+		Type:      event,
+		TaskID:    task.ID,
+		Status:    task.Status,
+		CreatedAt: time.Now().UTC(),
+		Data:      raw,
+	}
+	if b.bus == nil {
+		b.Hub.Publish(e)
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err == nil && len(payload) > maxPayload {
+		e.Data = nil
+		payload, err = json.Marshal(e)
+	}
+	if err != nil {
+		b.log.Error().Err(err).Str("event", event).Msg("Failed to encode stream event")
+		return
+	}
+	if err := b.bus.PublishEvent(payload); err != nil {
+		b.log.Error().Err(err).Str("event", event).Msg("Failed to publish stream event")
+	}
+}
+
+// Run слушает шину до отмены ctx и переподключается после ошибок.
+// Без шины возвращается сразу
+func (b *Broker) Run(ctx context.Context) {
+	if b.bus == nil {
+		return
+	}
+	for {
+		err := b.bus.ListenEvents(ctx, func(payload []byte) {
+			var e Event
+			if err := json.Unmarshal(payload, &e); err != nil {
+				b.log.Error().Err(err).Msg("Invalid stream event")
+				return
+			}
+			b.Hub.Publish(e)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		b.log.Error().Err(err).Msg("Stream event listener failed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Пакет users читает пользователей из сервиса пользователей (day04/2) по HTTP
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Сколько помнить найденного пользователя. Пользователи меняются редко,
+// а проверка исполнителя нужна почти каждому изменению задачи
+const cacheTTL = time.Minute
+
+type cached struct {
+	user    models.User
+	expires time.Time
+}
+
+type Client struct {
+	baseURL string
+	http    *http.Client
+
+	mu     sync.Mutex
+	byID   map[string]cached
+	byName map[string]cached
+}
+
+// baseURL - адрес сервиса пользователей, например http://localhost:8081
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: baseURL,
+		http:    &http.Client{Timeout: timeout},
+		byID:    make(map[string]cached),
+		byName:  make(map[string]cached),
+	}
+}
+
+// Пользователь по ID. Удалённые (в корзине) не находятся
+func (c *Client) GetUser(id string) (models.User, error) {
+	if user, ok := c.lookup(c.byID, id); ok {
+		return user, nil
+	}
+	var resp struct {
+		User models.User `json:"user"`
+	}
+	if err := c.get("/users/"+url.PathEscape(id), &resp); err != nil {
+		return models.User{}, err
+	}
+	c.store(resp.User)
+	return resp.User, nil
+}
+
+// Пользователь по имени - как в claim username токена. Сервис пользователей
+// держит имена уникальными; если под именем всё же несколько пользователей,
+// выбрать нельзя - models.ErrAmbiguousUser
+func (c *Client) FindUser(name string) (models.User, error) {
+	if user, ok := c.lookup(c.byName, name); ok {
+		return user, nil
+	}
+	var resp struct {
+		Users []models.User `json:"users"`
+	}
+	if err := c.get("/users?name="+url.QueryEscape(name), &resp); err != nil {
+		return models.User{}, err
+	}
+	switch len(resp.Users) {
+	case 0:
+		return models.User{}, models.ErrUserNotFound
+	case 1:
+	default:
+		return models.User{}, models.ErrAmbiguousUser
+	}
+	c.store(resp.Users[0])
+	return resp.Users[0], nil
+}
+
+func (c *Client) get(path string, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("user service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return models.ErrUserNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("user service responded %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid user service response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) lookup(cache map[string]cached, key string) (models.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(cache, key)
+		return models.User{}, false
+	}
+	return entry.user, true
+}
+
+func (c *Client) store(user models.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := cached{user: user, expires: time.Now().Add(cacheTTL)}
+	c.byID[user.ID] = entry
+	c.byName[user.Name] = entry
+}
@@ -0,0 +1,22 @@
+// Пакет webhook описывает события сервиса задач, на которые можно подписаться.
+// Рассылает их общий диспетчер day04/shared/webhook
+package webhook
+
+import "github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+
+// События сервиса задач
+const (
+	TaskCreated  = "task.created"
+	TaskUpdated  = "task.updated"
+	TaskDeleted  = "task.deleted"
+	TaskRestored = "task.restored"
+	// Сменился исполнитель задачи (приходит вместе с task.updated)
+	TaskAssigned = "task.assigned"
+	// Подписка на все события
+	AllEvents = webhook.AllEvents
+)
+
+// Типы событий, на которые можно подписаться
+var Events = map[string]bool{
+	TaskCreated: true, TaskUpdated: true, TaskDeleted: true, TaskRestored: true, TaskAssigned: true, AllEvents: true,
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS tasks (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    title       text NOT NULL,
+    description text NOT NULL
+);
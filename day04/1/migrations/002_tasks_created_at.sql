@@ -0,0 +1,5 @@
+-- Время создания нужно для сортировки и keyset-пагинации GET /tasks
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS tasks_created_at_id_idx ON tasks (created_at, id);
+CREATE INDEX IF NOT EXISTS tasks_title_id_idx ON tasks (title, id);
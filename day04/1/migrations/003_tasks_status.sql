@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'todo'
+    CHECK (status IN ('todo', 'in_progress', 'review', 'done', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS tasks_status_idx ON tasks (status);
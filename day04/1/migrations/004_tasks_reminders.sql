@@ -0,0 +1,11 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS due_at           timestamptz,
+    ADD COLUMN IF NOT EXISTS remind_at        timestamptz,
+    -- Когда напоминание было забрано планировщиком одной из реплик
+    ADD COLUMN IF NOT EXISTS reminder_sent_at timestamptz,
+    ADD COLUMN IF NOT EXISTS overdue_sent_at  timestamptz;
+
+CREATE INDEX IF NOT EXISTS tasks_remind_idx ON tasks (COALESCE(remind_at, due_at))
+    WHERE reminder_sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_overdue_idx ON tasks (due_at)
+    WHERE overdue_sent_at IS NULL;
@@ -0,0 +1,9 @@
+-- task_id нельзя начинать, пока не выполнена depends_on
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id    uuid NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    depends_on uuid NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, depends_on),
+    CHECK (task_id <> depends_on)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_depends_on_idx ON task_dependencies (depends_on);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id uuid REFERENCES tasks (id);
+
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id);
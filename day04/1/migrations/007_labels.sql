@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS labels (
+    id    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name  text NOT NULL UNIQUE,
+    color text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS task_labels (
+    task_id  uuid NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    label_id uuid NOT NULL REFERENCES labels (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON task_labels (label_id);
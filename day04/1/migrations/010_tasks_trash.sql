@@ -0,0 +1,6 @@
+-- Мягкое удаление: задача попадает в корзину и удаляется окончательно
+-- после срока хранения
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at)
+    WHERE deleted_at IS NOT NULL;
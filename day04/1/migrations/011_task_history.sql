@@ -0,0 +1,13 @@
+-- Журнал изменений задач. Внешнего ключа нет: история остаётся
+-- и после окончательного удаления задачи из корзины
+CREATE TABLE IF NOT EXISTS task_history (
+    id        bigserial PRIMARY KEY,
+    task_id   uuid        NOT NULL,
+    actor     text        NOT NULL,
+    operation text        NOT NULL,
+    at        timestamptz NOT NULL DEFAULT now(),
+    version   bigint      NOT NULL,
+    diff      jsonb       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_history_task_id_idx ON task_history (task_id, id);
@@ -0,0 +1,8 @@
+-- Повторяющиеся задачи: правило RRULE и ссылка экземпляра на первую задачу серии
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS recurrence text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS series_id  uuid REFERENCES tasks (id) ON DELETE SET NULL;
+
+-- Один экземпляр серии на дату: повторное выполнение не плодит дубликаты
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_series_due_idx ON tasks (series_id, due_at)
+    WHERE series_id IS NOT NULL;
@@ -0,0 +1,14 @@
+-- Комментарии к задачам. parent_id - комментарий, на который это ответ;
+-- при удалении комментария ответы удаляются вместе с ним
+CREATE TABLE IF NOT EXISTS comments (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id    uuid        NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    parent_id  uuid REFERENCES comments (id) ON DELETE CASCADE,
+    author     text        NOT NULL,
+    body       text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS comments_task_id_idx ON comments (task_id, created_at);
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments (parent_id);
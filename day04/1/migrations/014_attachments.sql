@@ -0,0 +1,14 @@
+-- Описания вложений задач; содержимое лежит в хранилище файлов под ключом
+-- tasks/<task_id>/<id>
+CREATE TABLE IF NOT EXISTS attachments (
+    id           uuid PRIMARY KEY,
+    task_id      uuid        NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    name         text        NOT NULL,
+    content_type text        NOT NULL,
+    size         bigint      NOT NULL CHECK (size >= 0),
+    checksum     text        NOT NULL,
+    uploaded_by  text        NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS attachments_task_id_idx ON attachments (task_id, created_at);
@@ -0,0 +1,3 @@
+-- Номера событий потока задач (GET /tasks/stream), общие для всех экземпляров
+-- сервиса: по ним клиент продолжает поток с Last-Event-ID на любом из них
+CREATE SEQUENCE IF NOT EXISTS task_events_id_seq;
@@ -0,0 +1,8 @@
+-- Исполнитель и автор задачи - ID пользователей из сервиса пользователей.
+-- Пользователи живут в другой базе, поэтому внешних ключей нет: ID проверяет сервер
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS assignee_id uuid,
+    ADD COLUMN IF NOT EXISTS reporter_id uuid;
+
+CREATE INDEX IF NOT EXISTS tasks_assignee_idx ON tasks (assignee_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_reporter_idx ON tasks (reporter_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,26 @@
+-- Проекты и их канбан-доски. Колонки доски - JSON-массив
+-- [{"name": "...", "statuses": ["todo", ...]}, ...] слева направо
+CREATE TABLE IF NOT EXISTS projects (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        text        NOT NULL,
+    description text        NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS boards (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id uuid        NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    name       text        NOT NULL,
+    columns    jsonb       NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS boards_project_idx ON boards (project_id, created_at);
+
+-- Место задачи на доске - лексикографический ранг; сравнивается побайтно (COLLATE "C"),
+-- как строки в Go. Перемещение карточки меняет ранг только у неё
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS project_id uuid REFERENCES projects (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS rank       text COLLATE "C" NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS tasks_project_rank_idx ON tasks (project_id, rank, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,16 @@
+-- Учёт времени по задачам. ended_at пустой, пока идёт таймер;
+-- у пользователя одновременно идёт не больше одного таймера
+CREATE TABLE IF NOT EXISTS time_entries (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id    uuid        NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    user_name  text        NOT NULL,
+    started_at timestamptz NOT NULL,
+    ended_at   timestamptz,
+    note       text        NOT NULL DEFAULT '',
+    manual     boolean     NOT NULL DEFAULT false,
+    CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS time_entries_running_idx ON time_entries (user_name) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS time_entries_task_id_idx ON time_entries (task_id, started_at);
+CREATE INDEX IF NOT EXISTS time_entries_started_at_idx ON time_entries (started_at);
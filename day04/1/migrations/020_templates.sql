@@ -0,0 +1,10 @@
+-- Шаблоны задач: дерево задач с подстановками {{name}} в jsonb
+-- и имена подстановок, которые нужно заполнить при создании задач
+CREATE TABLE IF NOT EXISTS templates (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        text        NOT NULL,
+    description text        NOT NULL DEFAULT '',
+    tasks       jsonb       NOT NULL,
+    variables   text[]      NOT NULL DEFAULT '{}',
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,16 @@
+-- Сохранённые представления списка задач. owner и shared_with - имена
+-- пользователей из JWT
+CREATE TABLE IF NOT EXISTS views (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner       text        NOT NULL,
+    name        text        NOT NULL,
+    query       text        NOT NULL DEFAULT '',
+    sort        text        NOT NULL DEFAULT '',
+    columns     text[]      NOT NULL DEFAULT '{}',
+    shared_with text[]      NOT NULL DEFAULT '{}',
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS views_owner_idx ON views (owner);
+CREATE INDEX IF NOT EXISTS views_shared_with_idx ON views USING gin (shared_with);
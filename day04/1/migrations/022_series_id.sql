@@ -0,0 +1,8 @@
+-- Серия повторяющейся задачи - отдельный ID, а не ссылка на первую задачу:
+-- окончательное удаление первой задачи больше не обнуляет series_id у всей серии
+-- и не ломает защиту от дубликатов по (series_id, due_at)
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_series_id_fkey;
+
+-- У первых задач существующих серий ID серии совпадает с их собственным ID,
+-- как у ссылок их экземпляров
+UPDATE tasks SET series_id = id WHERE series_id IS NULL AND recurrence <> '';
@@ -0,0 +1,4 @@
+-- Сортировка по названию побайтная (COLLATE "C"), как в хранилище в памяти.
+-- Индекс в правиле сортировки базы данных такому ORDER BY не подходит
+DROP INDEX IF EXISTS tasks_title_id_idx;
+CREATE INDEX IF NOT EXISTS tasks_title_c_id_idx ON tasks ((title COLLATE "C"), id);
@@ -5,11 +5,14 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/go-playground/validator/v10"
 
+	"github.com/lahnasti/GO_praktikum/day04/shared/auth"
+	"github.com/lahnasti/GO_praktikum/day04/shared/webhook"
 	"github.com/lahnasti/GO_praktikum/internal/config"
 	"github.com/lahnasti/GO_praktikum/internal/repository"
+	"github.com/lahnasti/GO_praktikum/internal/scheduler"
 	"github.com/lahnasti/GO_praktikum/internal/server"
 	"github.com/rs/zerolog/log"
 )
@@ -17,7 +20,10 @@ import (
 func main() {
 	log.Info().Msg("Service started")
 
-	cfg := config.ReadConfig()
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		panic(err)
+	}
 	fmt.Println(cfg)
 
 	conn, err := initDB(cfg.DBAddr)
@@ -26,32 +32,83 @@ func main() {
 	}
 	//repo := repository.New()
 	storage := repository.NewDB(conn)
+	//webhooks := webhook.NewStorage()
+	webhooks := webhook.NewDBStorage(conn)
 
 	log.Debug().Any("storage", storage).Msg("Check new storage")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Очистка корзины от пользователей старше срока хранения
+	purger := scheduler.NewPurger(&storage, cfg.PurgeInterval, cfg.TrashRetention, &log.Logger)
+	go purger.Run(ctx)
+
+	// Рассылка событий подписчикам вебхуков
+	events := webhook.New(&webhooks, webhook.Config{
+		Interval:    cfg.WebhookInterval,
+		Timeout:     cfg.WebhookTimeout,
+		Backoff:     cfg.WebhookBackoff,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Targets:     webhook.Targets{Hosts: cfg.WebhookHosts, AllowPrivate: cfg.WebhookAllowPrivate},
+	}, &log.Logger)
+	go events.Run(ctx)
+
 	validate := validator.New()  // Инициализация валидатора
 
+	adminOnly := server.AdminOnly(cfg.Admins)
+
 	server := server.Server{
 		Db: &storage,
 		Valid: validate,
+		Webhooks: &webhooks,
+		Events: events,
 	}
 	log.Debug().Any("server", server).Msg("Check new server")
 
 	r := gin.Default()
 
-	r.POST("/users", server.RegisterUser)
+	// Чтение открыто: его использует сервис задач. Изменения при заданном ключе
+	// требуют JWT, автор в журнале - пользователь из токена. Регистрация открыта
+	// всем: токен, если он есть, только указывает автора
+	writes := r.Group("/users")
+	register := r.Group("/users")
+	if cfg.JWTSecret != "" {
+		writes.Use(auth.Middleware([]byte(cfg.JWTSecret)))
+		register.Use(auth.Optional([]byte(cfg.JWTSecret)))
+	}
+
+	register.POST("", server.RegisterUser)
 	r.GET("/users", server.GetUsersHandler)
 	r.GET("/users/:id", server.GetUserByIDHandler)
-	r.PUT("/users/:id", server.UpdateUserHandler)
-	r.DELETE("/users/:id", server.DeleteUserHandler)
+	writes.PUT("/:id", server.UpdateUserHandler)
+	writes.PATCH("/:id", server.PatchUserHandler)
+	writes.DELETE("/:id", server.DeleteUserHandler)
+	writes.POST("/:id/restore", server.RestoreUserHandler)
+	r.GET("/users/:id/history", server.GetHistoryHandler)
+
+	r.GET("/trash", server.GetTrashHandler)
+
+	// Администраторы - пользователи из JWT, как в сервисе задач
+	admin := r.Group("/webhooks")
+	if cfg.JWTSecret != "" {
+		admin.Use(auth.Middleware([]byte(cfg.JWTSecret)))
+	}
+	admin.Use(adminOnly)
+	admin.GET("", server.GetWebhooksHandler)
+	admin.POST("", server.AddWebhookHandler)
+	admin.GET("/:id", server.GetWebhookHandler)
+	admin.DELETE("/:id", server.DeleteWebhookHandler)
+	admin.GET("/:id/deliveries", server.GetDeliveriesHandler)
+	admin.POST("/:id/deliveries/:delivery/redeliver", server.RedeliverHandler)
 
 	if err := r.Run(cfg.Addr); err != nil {
 		panic(err)
 	}
 }
 
-func initDB(addr string) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(context.Background(), addr)
+func initDB(addr string) (*pgxpool.Pool, error) {
+	conn, err := pgxpool.New(context.Background(), addr)
 	if err != nil {
 		return nil,
 			fmt.Errorf("database initialization error: %w", err)
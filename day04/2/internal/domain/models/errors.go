@@ -0,0 +1,17 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// Пользователь изменился с момента чтения: версия не совпадает с ожидаемой
+	ErrVersionMismatch = errors.New("version mismatch")
+	// Имя занято другим пользователем вне корзины
+	ErrNameTaken        = errors.New("user name is already taken")
+	ErrWebhookNotFound  = webhook.ErrWebhookNotFound
+	ErrDeliveryNotFound = webhook.ErrDeliveryNotFound
+)
@@ -1,8 +1,62 @@
 package models
 
+import (
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+)
+
 type User struct {
-	ID string `json:"id"`
-	Name string `json:"name" validate:"required"`
-	Email string `json:"email" validate:"required,email"`
+	ID       string `json:"id"`
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
-}
\ No newline at end of file
+	// Номер версии, растёт при каждом изменении. Отдаётся в ETag
+	Version int64 `json:"version"`
+	// Когда пользователь попал в корзину. У пользователей вне корзины пусто
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Версия для операций без проверки версии
+const AnyVersion int64 = 0
+
+// Подписка на события и доставка события подписчику. Рассылка общая
+// для сервисов day04 и живёт в day04/shared/webhook
+type (
+	Webhook  = webhook.Webhook
+	Delivery = webhook.Delivery
+)
+
+// Операция над сущностью в журнале изменений
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+	// Окончательное удаление из корзины
+	AuditPurge AuditOperation = "purge"
+)
+
+// Автор изменений, которые сервис делает сам (очистка корзины)
+const SystemActor = "system"
+
+// Запись журнала изменений. Diff содержит только изменившиеся поля
+type AuditRecord struct {
+	ID        int64          `json:"id"`
+	EntityID  string         `json:"entity_id"`
+	Actor     string         `json:"actor"`
+	Operation AuditOperation `json:"operation"`
+	At        time.Time      `json:"at"`
+	// Версия сущности после изменения
+	Version int64                  `json:"version"`
+	Diff    map[string]FieldChange `json:"diff"`
+}
+
+// Старое и новое значение поля. Для новой сущности Old пустое.
+// Значения секретных полей (пароль) в журнал не попадают
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Служебные поля пользователя, которые не попадают в diff: ID есть в самой записи,
+// версия - отдельным полем
+var auditSkip = map[string]bool{"id": true, "version": true}
+
+// Поля, значения которых в журнале заменяются на redacted:
+// видно, что пароль меняли, но не сам пароль
+var auditSecret = map[string]bool{"password": true}
+
+const redacted = "[redacted]"
+
+// Журнал изменений пользователя в порядке записи
+func (stor *Repository) GetHistory(id string) ([]models.AuditRecord, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	records := []models.AuditRecord{}
+	for _, record := range stor.history {
+		if record.EntityID == id {
+			records = append(records, record)
+		}
+	}
+	if len(records) == 0 {
+		return nil, models.ErrUserNotFound
+	}
+	return records, nil
+}
+
+// Дописывает запись в журнал; old - состояние пользователя до изменения (nil при создании).
+// Журнал только растёт, записи не меняются и не удаляются. Вызывается под stor.mu
+func (stor *Repository) audit(actor string, op models.AuditOperation, old *models.User, user models.User) error {
+	var before any
+	if old != nil {
+		before = *old
+	}
+	diff, err := fieldDiff(before, user)
+	if err != nil {
+		return err
+	}
+	stor.history = append(stor.history, models.AuditRecord{
+		ID:        int64(len(stor.history) + 1),
+		EntityID:  user.ID,
+		Actor:     actor,
+		Operation: op,
+		At:        time.Now().UTC(),
+		Version:   user.Version,
+		Diff:      diff,
+	})
+	return nil
+}
+
+// Поля, которые различаются в JSON-представлении old и new.
+// old равен nil для новой сущности: тогда в diff попадают все заполненные поля
+func fieldDiff(old, new any) (map[string]models.FieldChange, error) {
+	before, err := jsonFields(old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := jsonFields(new)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(map[string]models.FieldChange)
+	for field, value := range after {
+		if !auditSkip[field] && !reflect.DeepEqual(before[field], value) {
+			diff[field] = models.FieldChange{Old: before[field], New: value}
+		}
+	}
+	// Поля с omitempty, которые стали пустыми
+	for field, value := range before {
+		if _, exists := after[field]; !exists && !auditSkip[field] {
+			diff[field] = models.FieldChange{Old: value}
+		}
+	}
+	for field, change := range diff {
+		if auditSecret[field] {
+			if change.Old != nil {
+				change.Old = redacted
+			}
+			if change.New != nil {
+				change.New = redacted
+			}
+			diff[field] = change
+		}
+	}
+	return diff, nil
+}
+
+func jsonFields(v any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if v == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
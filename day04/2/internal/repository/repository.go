@@ -1,31 +1,50 @@
 package repository
 
 import (
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
-
-	"errors"
 )
 
 type Repository struct {
+	mu sync.RWMutex
 	db map[string]models.User
+	// Корзина: удалённые пользователи до окончательной очистки
+	trash map[string]models.User
+	// Журнал изменений пользователей (только дописывается)
+	history []models.AuditRecord
 }
 
 func New() *Repository {
 	db := make(map[string]models.User)
 	return &Repository{
-		db: db,
+		db:    db,
+		trash: make(map[string]models.User),
 	}
 }
 
-func (stor *Repository) AddUser(data models.User) (string, error) {
+func (stor *Repository) AddUser(data models.User, actor string) (string, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if stor.nameTaken(data.Name, "") {
+		return "", models.ErrNameTaken
+	}
 	userID := uuid.New().String()
 	data.ID = userID
+	data.Version = 1
+	if err := stor.audit(actor, models.AuditCreate, nil, data); err != nil {
+		return "", err
+	}
 	stor.db[userID] = data
 	return userID, nil
 }
 
 func (stor *Repository) GetUsers() ([]models.User, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
 	var users []models.User
 	for _, user := range stor.db {
 		users = append(users, user)
@@ -33,28 +52,140 @@ func (stor *Repository) GetUsers() ([]models.User, error) {
 	return users, nil
 }
 
+// Пользователи вне корзины с этим именем. Имена уникальны, так что их не больше одного
+func (stor *Repository) GetUsersByName(name string) ([]models.User, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	var users []models.User
+	for _, user := range stor.db {
+		if user.Name == name {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
 func (stor *Repository) GetUserByID(id string) (models.User, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
 	user, exists := stor.db[id]
 	if !exists {
-		return models.User{}, errors.New("user not found")
+		return models.User{}, models.ErrUserNotFound
 	}
 	return user, nil
 }
 
-func (stor *Repository) UpdateUser(id string, user models.User) error {
-	if _, exists := stor.db[id]; !exists {
-		return errors.New("user not found")
+// Сохраняет пользователя. Если user.Version не AnyVersion, она должна совпадать с текущей
+func (stor *Repository) UpdateUser(id string, user models.User, actor string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	old, exists := stor.db[id]
+	if !exists {
+		return models.ErrUserNotFound
+	}
+	if user.Version != models.AnyVersion && user.Version != old.Version {
+		return models.ErrVersionMismatch
+	}
+	if stor.nameTaken(user.Name, id) {
+		return models.ErrNameTaken
 	}
 	user.ID = id
+	user.Version = old.Version + 1
+	if err := stor.audit(actor, models.AuditUpdate, &old, user); err != nil {
+		return err
+	}
 	stor.db[id] = user
 	return nil
 
 }
 
-func (stor *Repository) DeleteUser(id string) error {
-	if _, exists := stor.db[id]; !exists {
-		return errors.New("user not found")
+// Переносит пользователя в корзину. Если version не AnyVersion, она должна совпадать с текущей
+func (stor *Repository) DeleteUser(id string, version int64, actor string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	user, exists := stor.db[id]
+	if !exists {
+		return models.ErrUserNotFound
+	}
+	if version != models.AnyVersion && version != user.Version {
+		return models.ErrVersionMismatch
+	}
+	old := user
+	now := time.Now().UTC()
+	user.DeletedAt = &now
+	user.Version++
+	if err := stor.audit(actor, models.AuditDelete, &old, user); err != nil {
+		return err
 	}
 	delete(stor.db, id)
+	stor.trash[id] = user
 	return nil
 }
+
+// Пользователи в корзине: сначала удалённые последними
+func (stor *Repository) GetTrash() ([]models.User, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	users := []models.User{}
+	for _, user := range stor.trash {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].DeletedAt.Equal(*users[j].DeletedAt) {
+			return users[i].DeletedAt.After(*users[j].DeletedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+// Возвращает пользователя из корзины
+func (stor *Repository) RestoreUser(id, actor string) (models.User, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	user, exists := stor.trash[id]
+	if !exists {
+		return models.User{}, models.ErrUserNotFound
+	}
+	if stor.nameTaken(user.Name, id) {
+		return models.User{}, models.ErrNameTaken
+	}
+	old := user
+	user.DeletedAt = nil
+	user.Version++
+	if err := stor.audit(actor, models.AuditRestore, &old, user); err != nil {
+		return models.User{}, err
+	}
+	delete(stor.trash, id)
+	stor.db[id] = user
+	return user, nil
+}
+
+// Окончательно удаляет пользователей, попавших в корзину не позже before.
+// Журнал изменений пользователей сохраняется
+func (stor *Repository) PurgeDeleted(before time.Time) (int, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	purged := 0
+	for id, user := range stor.trash {
+		if !user.DeletedAt.After(before) {
+			if err := stor.audit(models.SystemActor, models.AuditPurge, &user, user); err != nil {
+				return purged, err
+			}
+			delete(stor.trash, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Есть ли вне корзины другой пользователь (не exceptID) с таким именем.
+// Вызывается под stor.mu
+func (stor *Repository) nameTaken(name, exceptID string) bool {
+	for id, user := range stor.db {
+		if id != exceptID && user.Name == name {
+			return true
+		}
+	}
+	return false
+}
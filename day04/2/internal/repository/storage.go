@@ -2,19 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
 )
 
 type DBstorage struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
 }
 
-func NewDB(conn *pgx.Conn) DBstorage {
+func NewDB(conn *pgxpool.Pool) DBstorage {
 	return DBstorage {
 		conn: conn,
 	}
@@ -23,68 +26,250 @@ func NewDB(conn *pgx.Conn) DBstorage {
 func (db *DBstorage) GetUsers() ([]models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	rows, err := db.conn.Query(ctx, "SELECT id, name, email, password FROM users")
+	rows, err := db.conn.Query(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var users []models.User
 	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Password); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, err
 		}
-		user.Name = strings.TrimSpace(user.Name)
-		user.Email = strings.TrimSpace(user.Email)
-		user.Password = strings.TrimSpace(user.Password)
 		users = append(users, user)
 	}
 	return users, nil
 }
 
-func (db *DBstorage) GetUserByID(id string) (models.User, error) {
+// Пользователи вне корзины с этим именем. Имена уникальны (индекс users_name_key),
+// так что их не больше одного
+func (db *DBstorage) GetUsersByName(name string) ([]models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	row := db.conn.QueryRow(ctx, "SELECT name, email, password FROM users WHERE id=$1", id)
+	rows, err := db.conn.Query(ctx, "SELECT "+userColumns+" FROM users WHERE name = $1 AND deleted_at IS NULL", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Колонки пользователя в порядке, который ожидает scanUser
+const userColumns = "id, name, email, password, version, deleted_at"
+
+func scanUser(row pgx.Row) (models.User, error) {
 	var user models.User
-	if err := row.Scan(&user.Name, &user.Email, &user.Password); err != nil {
+	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Version, &user.DeletedAt); err != nil {
 		return models.User{}, err
 	}
+	user.Name = strings.TrimSpace(user.Name)
+	user.Email = strings.TrimSpace(user.Email)
+	user.Password = strings.TrimSpace(user.Password)
+	return user, nil
+}
+
+func (db *DBstorage) GetUserByID(id string) (models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	row := db.conn.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1 AND deleted_at IS NULL", id)
+	user, err := scanUser(row)
+	if err != nil {
+		return models.User{}, notFound(err)
+	}
 	return user, nil
 }
 
-func (db *DBstorage) AddUser(user models.User) (string, error) {
+func (db *DBstorage) AddUser(user models.User, actor string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	query := "INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id"
-	var userID string
-	err := db.conn.QueryRow(ctx, query, user.Name, user.Email, user.Password).Scan(&userID)
+	tx, err := db.conn.Begin(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to insert user: %w", err)
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	query := "INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING " + userColumns
+	created, err := scanUser(tx.QueryRow(ctx, query, user.Name, user.Email, user.Password))
+	if err != nil {
+		return "", fmt.Errorf("failed to insert user: %w", nameTaken(err))
 	}
 	// Проверка, что userID не пустой
-	if userID == "" {
+	if created.ID == "" {
 		return "", fmt.Errorf("failed to get userID after insert")
 	}
-	return userID, nil
+	if err := writeAudit(ctx, tx, actor, models.AuditCreate, nil, created); err != nil {
+		return "", err
+	}
+	return created.ID, tx.Commit(ctx)
 }
 
-func (db *DBstorage) UpdateUser(id string, user models.User) error {
+// Сохраняет пользователя. Если user.Version не AnyVersion, она должна совпадать с текущей:
+// строка блокируется на время проверки и записи
+func (db *DBstorage) UpdateUser(id string, user models.User, actor string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := db.conn.Exec(ctx, "UPDATE users SET name=$1, email=$2, password=$3 WHERE id=$4", user.Name, user.Email, user.Password, id)
+	tx, err := db.conn.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("update user failed: %w", err)
+		return err
 	}
-	return nil
+	defer tx.Rollback(ctx)
+	old, err := lockUser(ctx, tx, id, user.Version)
+	if err != nil {
+		return err
+	}
+	updated, err := scanUser(tx.QueryRow(ctx, `UPDATE users SET name=$1, email=$2, password=$3, version = version + 1
+		WHERE id=$4 RETURNING `+userColumns, user.Name, user.Email, user.Password, id))
+	if err != nil {
+		return fmt.Errorf("update user failed: %w", nameTaken(notFound(err)))
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditUpdate, &old, updated); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (db *DBstorage) DeleteUser(id string) error {
+// Переносит пользователя в корзину. Если version не AnyVersion, она должна совпадать с текущей
+func (db *DBstorage) DeleteUser(id string, version int64, actor string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := db.conn.Exec(ctx, "DELETE FROM users WHERE id=$1", id)
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	old, err := lockUser(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+	deleted, err := scanUser(tx.QueryRow(ctx, `UPDATE users SET deleted_at = now(), version = version + 1
+		WHERE id=$1 RETURNING `+userColumns, id))
+	if err != nil {
+		return fmt.Errorf("delete user failed: %w", notFound(err))
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditDelete, &old, deleted); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Блокирует пользователя вне корзины до конца транзакции и проверяет его версию
+// (если version не AnyVersion). Возвращает состояние до изменения
+func lockUser(ctx context.Context, tx pgx.Tx, id string, version int64) (models.User, error) {
+	user, err := scanUser(tx.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id))
 	if err != nil {
-		return fmt.Errorf("delete user failed: %w", err)
+		return models.User{}, notFound(err)
+	}
+	if version != models.AnyVersion && version != user.Version {
+		return models.User{}, models.ErrVersionMismatch
+	}
+	return user, nil
+}
+
+// Пользователи в корзине: сначала удалённые последними
+func (db *DBstorage) GetTrash() ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+	if err != nil {
+		return nil, fmt.Errorf("get trash failed: %w", err)
+	}
+	defer rows.Close()
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Возвращает пользователя из корзины
+func (db *DBstorage) RestoreUser(id, actor string) (models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback(ctx)
+	old, err := scanUser(tx.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1 AND deleted_at IS NOT NULL FOR UPDATE", id))
+	if err != nil {
+		return models.User{}, notFound(err)
+	}
+	user, err := scanUser(tx.QueryRow(ctx, `UPDATE users SET deleted_at = NULL, version = version + 1
+		WHERE id=$1 RETURNING `+userColumns, id))
+	if err != nil {
+		return models.User{}, fmt.Errorf("restore user failed: %w", nameTaken(err))
+	}
+	if err := writeAudit(ctx, tx, actor, models.AuditRestore, &old, user); err != nil {
+		return models.User{}, err
+	}
+	return user, tx.Commit(ctx)
+}
+
+// Окончательно удаляет пользователей, попавших в корзину не позже before.
+// Журнал изменений пользователей сохраняется
+func (db *DBstorage) PurgeDeleted(before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, "DELETE FROM users WHERE deleted_at <= $1 RETURNING "+userColumns, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge users failed: %w", err)
+	}
+	var purged []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		purged = append(purged, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("purge users failed: %w", err)
+	}
+	for _, user := range purged {
+		if err := writeAudit(ctx, tx, models.SystemActor, models.AuditPurge, &user, user); err != nil {
+			return 0, err
+		}
+	}
+	return len(purged), tx.Commit(ctx)
+}
+
+// Приводит ошибки "строки нет" к models.ErrUserNotFound: пустой результат
+// и ID, который не является UUID
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrUserNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+		return models.ErrUserNotFound
+	}
+	return err
+}
+
+// Нарушение уникальности имени (индекс users_name_key) - models.ErrNameTaken
+func nameTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "users_name_key" {
+		return models.ErrNameTaken
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Журнал изменений пользователя в порядке записи
+func (db *DBstorage) GetHistory(id string) ([]models.AuditRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `SELECT id, user_id, actor, operation, at, version, diff
+		FROM user_history WHERE user_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	defer rows.Close()
+	records := []models.AuditRecord{}
+	for rows.Next() {
+		var r models.AuditRecord
+		if err := rows.Scan(&r.ID, &r.EntityID, &r.Actor, &r.Operation, &r.At, &r.Version, &r.Diff); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, notFound(err)
+	}
+	if len(records) == 0 {
+		return nil, models.ErrUserNotFound
+	}
+	return records, nil
+}
+
+// Пишет запись журнала в транзакции изменения; old - состояние пользователя
+// до изменения (nil при создании)
+func writeAudit(ctx context.Context, tx pgx.Tx, actor string, op models.AuditOperation, old *models.User, user models.User) error {
+	var before any
+	if old != nil {
+		before = *old
+	}
+	diff, err := fieldDiff(before, user)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO user_history (user_id, actor, operation, version, diff) VALUES ($1, $2, $3, $4, $5)",
+		user.ID, actor, string(op), user.Version, diff)
+	if err != nil {
+		return fmt.Errorf("write audit failed: %w", err)
+	}
+	return nil
+}
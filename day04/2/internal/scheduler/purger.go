@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type TrashRepository interface {
+	PurgeDeleted(before time.Time) (int, error)
+}
+
+// Purger периодически окончательно удаляет пользователей,
+// которые пролежали в корзине дольше срока хранения
+type Purger struct {
+	repo      TrashRepository
+	interval  time.Duration
+	retention time.Duration
+	log       *zerolog.Logger
+}
+
+// interval - период очистки, retention - сколько пользователь хранится в корзине
+func NewPurger(repo TrashRepository, interval, retention time.Duration, zlog *zerolog.Logger) *Purger {
+	return &Purger{
+		repo:      repo,
+		interval:  interval,
+		retention: retention,
+		log:       zlog,
+	}
+}
+
+// Run работает до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge() {
+	purged, err := p.repo.PurgeDeleted(time.Now().UTC().Add(-p.retention))
+	if err != nil {
+		p.log.Error().Err(err).Msg("Failed to purge trash")
+		return
+	}
+	if purged > 0 {
+		p.log.Info().Int("count", purged).Msg("Trash purged")
+	}
+}
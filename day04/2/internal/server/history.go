@@ -0,0 +1,37 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/day04/shared/auth"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+)
+
+// Автор изменений, если пользователь не известен (авторизация выключена)
+const anonymousActor = "anonymous"
+
+// Кто выполняет запрос - для журнала изменений. Только проверенный пользователь
+// из JWT: заголовкам клиента журнал не доверяет
+func actor(ctx *gin.Context) string {
+	if name := auth.Username(ctx); name != "" {
+		return name
+	}
+	return anonymousActor
+}
+
+// GET /users/:id/history - журнал изменений пользователя: кто, когда и какие поля менял.
+// Доступен и для пользователей в корзине, и для окончательно удалённых
+func (s *Server) GetHistoryHandler(ctx *gin.Context) {
+	records, err := s.Db.GetHistory(ctx.Param("id"))
+	if errors.Is(err, models.ErrUserNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "User history", "history": records})
+}
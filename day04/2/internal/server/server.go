@@ -1,29 +1,46 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
 )
 
 type Repository interface {
-	AddUser(models.User) (string, error)
+	AddUser(user models.User, actor string) (string, error)
 	GetUserByID(id string) (models.User, error)
 	GetUsers() ([]models.User, error)
-	UpdateUser(id string, user models.User) error
-	DeleteUser(id string) error
+	GetUsersByName(name string) ([]models.User, error)
+	UpdateUser(id string, user models.User, actor string) error
+	DeleteUser(id string, version int64, actor string) error
+	GetTrash() ([]models.User, error)
+	RestoreUser(id, actor string) (models.User, error)
+	GetHistory(id string) ([]models.AuditRecord, error)
 }
 
 type Server struct {
 	Db    Repository
 	Valid *validator.Validate
+	// Подписки на события и рассылка событий. Events пустой - события не отправляются
+	Webhooks WebhookRepository
+	Events   EventEmitter
 }
 
 
+// GET /users?name=<имя> - с параметром name только пользователь с этим именем
+// (по нему сервис задач находит пользователя из JWT). Имена вне корзины уникальны
 func (s *Server) GetUsersHandler(ctx *gin.Context) {
-	users, err := s.Db.GetUsers()
+	var users []models.User
+	var err error
+	if name, ok := ctx.GetQuery("name"); ok {
+		users, err = s.Db.GetUsersByName(name)
+	} else {
+		users, err = s.Db.GetUsers()
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -44,7 +61,11 @@ func (s *Server) RegisterUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
 		return
 	}
-	userID, err := s.Db.AddUser(user)
+	userID, err := s.Db.AddUser(user, actor(ctx))
+	if errors.Is(err, models.ErrNameTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Name is taken", "error": err.Error()})
+		return
+	}
 	if err != nil {
 		// Проверка на ошибку доступа к таблице users
        // if strings.Contains(err.Error(), "permission denied for table users") {
@@ -53,6 +74,9 @@ func (s *Server) RegisterUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to save user"})
 		return
 	}
+	if created, err := s.Db.GetUserByID(userID); err == nil {
+		s.emitUser(webhook.UserRegistered, created)
+	}
 	ctx.JSON(200, gin.H{"message": "User successfully registered", "user_id": userID})
 	
 }
@@ -65,6 +89,11 @@ func (s *Server) GetUserByIDHandler(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.Header("ETag", etag(user.Version))
+	if match := ctx.GetHeader("If-None-Match"); match != "" && matchETag(match, user.Version, true) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "User retrieved", "user": user})
 }
 
@@ -76,22 +105,70 @@ func (s *Server) UpdateUserHandler(ctx *gin.Context) {
 	}
 
 	id := ctx.Param("id")
-	user.ID = id
-	err := s.Db.UpdateUser(id, user)
+	current, err := s.Db.GetUserByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	s.saveUser(ctx, current, user)
+}
+
+// Сохраняет новую версию пользователя current (общая часть PUT и PATCH).
+// Запись проходит, только если пользователь не изменился с момента чтения current
+func (s *Server) saveUser(ctx *gin.Context, current, user models.User) {
+	version, ok := checkIfMatch(ctx, current.Version)
+	if !ok {
+		return
+	}
+	user.ID = current.ID
+	user.Version = version
+	if err := s.Valid.Struct(user); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	err := s.Db.UpdateUser(user.ID, user, actor(ctx))
+	if errors.Is(err, models.ErrVersionMismatch) {
+		versionConflict(ctx)
+		return
+	}
+	if errors.Is(err, models.ErrNameTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Name is taken", "error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	user.Version++
+	s.emitUser(webhook.UserUpdated, user)
+	ctx.Header("ETag", etag(user.Version))
 	ctx.JSON(http.StatusOK, gin.H{"message": "User updated", "user": user})
 }
 
 func (s *Server) DeleteUserHandler(ctx *gin.Context) {
 	id := ctx.Param("id")
-	err := s.Db.DeleteUser(id)
+	version := models.AnyVersion
+	if match := ctx.GetHeader("If-Match"); match != "" {
+		current, err := s.Db.GetUserByID(id)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		var ok bool
+		if version, ok = checkIfMatch(ctx, current.Version); !ok {
+			return
+		}
+	}
+	err := s.Db.DeleteUser(id, version, actor(ctx))
+	if errors.Is(err, models.ErrVersionMismatch) {
+		versionConflict(ctx)
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(200, gin.H{"message": "User deleted", "user_id": id})
+	s.emit(webhook.UserDeleted, gin.H{"id": id})
+	ctx.JSON(200, gin.H{"message": "User moved to trash", "user_id": id})
 }
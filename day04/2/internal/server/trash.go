@@ -0,0 +1,40 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// GET /trash - удалённые пользователи, которых ещё можно восстановить
+func (s *Server) GetTrashHandler(ctx *gin.Context) {
+	users, err := s.Db.GetTrash()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Trash", "users": users})
+}
+
+// POST /users/:id/restore - возвращает пользователя из корзины
+func (s *Server) RestoreUserHandler(ctx *gin.Context) {
+	user, err := s.Db.RestoreUser(ctx.Param("id"), actor(ctx))
+	if errors.Is(err, models.ErrUserNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "User is not in trash", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrNameTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Name is taken by another user", "error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.emitUser(webhook.UserRestored, user)
+	ctx.Header("ETag", etag(user.Version))
+	ctx.JSON(http.StatusOK, gin.H{"message": "User restored", "user": user})
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/day04/shared/auth"
+	"github.com/lahnasti/GO_praktikum/internal/domain/models"
+	"github.com/lahnasti/GO_praktikum/internal/webhook"
+)
+
+// Хранилище подписок и журнала доставок
+type WebhookRepository interface {
+	AddWebhook(hook models.Webhook) (models.Webhook, error)
+	GetWebhooks() ([]models.Webhook, error)
+	GetWebhook(id string) (models.Webhook, error)
+	DeleteWebhook(id string) error
+	GetDeliveries(webhookID string) ([]models.Delivery, error)
+	Redeliver(webhookID, deliveryID string) (models.Delivery, error)
+}
+
+// Получатель событий сервиса (webhook.Dispatcher из day04/shared)
+type EventEmitter interface {
+	Emit(event string, data any)
+	// Можно ли отправлять события на этот адрес
+	CheckURL(rawURL string) error
+}
+
+// Пускает только администраторов из списка (пользователей из JWT, общая с
+// сервисом задач проверка). Пустой список не пускает никого: вебхуки
+// отправляют запросы от имени сервиса
+func AdminOnly(admins []string) gin.HandlerFunc {
+	return auth.AdminOnly(admins)
+}
+
+// Отправляет событие подписчикам, если рассылка настроена
+func (s *Server) emit(event string, data any) {
+	if s.Events != nil {
+		s.Events.Emit(event, data)
+	}
+}
+
+// Событие о пользователе. Пароль подписчикам не отправляется
+func (s *Server) emitUser(event string, user models.User) {
+	s.emit(event, gin.H{"id": user.ID, "name": user.Name, "email": user.Email, "version": user.Version})
+}
+
+// GET /webhooks
+func (s *Server) GetWebhooksHandler(ctx *gin.Context) {
+	hooks, err := s.Webhooks.GetWebhooks()
+	if err != nil {
+		s.webhookError(ctx, err)
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List webhooks", "webhooks": hooks})
+}
+
+// POST /webhooks {"url": "...", "events": ["user.registered"], "secret": "..."}.
+// Без secret ключ подписи генерируется; он возвращается только в этом ответе
+func (s *Server) AddWebhookHandler(ctx *gin.Context) {
+	var hook models.Webhook
+	if err := ctx.ShouldBindJSON(&hook); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid params", "error": err.Error()})
+		return
+	}
+	err := s.Valid.Struct(hook)
+	for _, event := range hook.Events {
+		if err == nil && !webhook.Events[event] {
+			err = fmt.Errorf("unknown event %q", event)
+		}
+	}
+	if err == nil && s.Events != nil {
+		err = s.Events.CheckURL(hook.URL)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data has not been validated", "error": err.Error()})
+		return
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			s.webhookError(ctx, err)
+			return
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+	hook.CreatedBy = actor(ctx)
+	hook, err = s.Webhooks.AddWebhook(hook)
+	if err != nil {
+		s.webhookError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Webhook added", "webhook": hook})
+}
+
+// GET /webhooks/:id
+func (s *Server) GetWebhookHandler(ctx *gin.Context) {
+	hook, err := s.Webhooks.GetWebhook(ctx.Param("id"))
+	if err != nil {
+		s.webhookError(ctx, err)
+		return
+	}
+	hook.Secret = ""
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook retrieved", "webhook": hook})
+}
+
+// DELETE /webhooks/:id - удаляет подписку вместе с журналом доставок
+func (s *Server) DeleteWebhookHandler(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if err := s.Webhooks.DeleteWebhook(id); err != nil {
+		s.webhookError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook deleted", "webhook_id": id})
+}
+
+// GET /webhooks/:id/deliveries - последние доставки, сначала новые
+func (s *Server) GetDeliveriesHandler(ctx *gin.Context) {
+	deliveries, err := s.Webhooks.GetDeliveries(ctx.Param("id"))
+	if err != nil {
+		s.webhookError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "List deliveries", "deliveries": deliveries})
+}
+
+// POST /webhooks/:id/deliveries/:delivery/redeliver - повторная отправка события
+// новой доставкой; уйдёт при следующей проверке очереди
+func (s *Server) RedeliverHandler(ctx *gin.Context) {
+	delivery, err := s.Webhooks.Redeliver(ctx.Param("id"), ctx.Param("delivery"))
+	if err != nil {
+		s.webhookError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued", "delivery": delivery})
+}
+
+func (s *Server) webhookError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound), errors.Is(err, models.ErrDeliveryNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
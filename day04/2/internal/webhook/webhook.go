@@ -0,0 +1,20 @@
+// Пакет webhook описывает события сервиса пользователей, на которые можно
+// подписаться. Рассылает их общий диспетчер day04/shared/webhook
+package webhook
+
+import "github.com/lahnasti/GO_praktikum/day04/shared/webhook"
+
+// События сервиса пользователей
+const (
+	UserRegistered = "user.registered"
+	UserUpdated    = "user.updated"
+	UserDeleted    = "user.deleted"
+	UserRestored   = "user.restored"
+	// Подписка на все события
+	AllEvents = webhook.AllEvents
+)
+
+// Типы событий, на которые можно подписаться
+var Events = map[string]bool{
+	UserRegistered: true, UserUpdated: true, UserDeleted: true, UserRestored: true, AllEvents: true,
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+    id       uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name     text NOT NULL,
+    email    text NOT NULL,
+    password text NOT NULL
+);
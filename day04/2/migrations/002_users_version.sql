@@ -0,0 +1,2 @@
+-- Версия для оптимистичных блокировок (ETag / If-Match)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
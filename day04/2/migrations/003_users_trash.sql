@@ -0,0 +1,6 @@
+-- Мягкое удаление: пользователь попадает в корзину и удаляется окончательно
+-- после срока хранения
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at)
+    WHERE deleted_at IS NOT NULL;
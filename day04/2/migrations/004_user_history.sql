@@ -0,0 +1,13 @@
+-- Журнал изменений пользователей. Внешнего ключа нет: история остаётся
+-- и после окончательного удаления пользователя из корзины
+CREATE TABLE IF NOT EXISTS user_history (
+    id        bigserial PRIMARY KEY,
+    user_id   uuid        NOT NULL,
+    actor     text        NOT NULL,
+    operation text        NOT NULL,
+    at        timestamptz NOT NULL DEFAULT now(),
+    version   bigint      NOT NULL,
+    diff      jsonb       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_history_user_id_idx ON user_history (user_id, id);
@@ -0,0 +1,27 @@
+-- Подписки на события сервиса. events - типы событий, '*' - все
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    url        text        NOT NULL,
+    events     text[]      NOT NULL,
+    secret     text        NOT NULL,
+    created_by text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Журнал доставок и очередь отправки: pending-доставки уходят, когда наступит next_attempt_at
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id      uuid        NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event           text        NOT NULL,
+    payload         jsonb       NOT NULL,
+    status          text        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        integer     NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz DEFAULT now(),
+    response_code   integer,
+    last_error      text,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    delivered_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Имя пользователя вне корзины уникально: по нему сервис задач находит
+-- пользователя из JWT (claim username). Если имена уже повторяются, их нужно
+-- переименовать до миграции, иначе индекс не создастся
+CREATE UNIQUE INDEX IF NOT EXISTS users_name_key ON users (name) WHERE deleted_at IS NULL;
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"flag"
+
 	"github.com/gin-gonic/gin"
 	"github.com/lahnasti/GO_praktikum/internal/server"
 )
 
 func main() {
+	var addr, secret string
+	flag.StringVar(&addr, "addr", ":8080", "Server address")
+	// Ключ по умолчанию - прежний встроенный, годится только для локального запуска
+	flag.StringVar(&secret, "jwt-secret", "your_secret_key", "HS256 key to sign JWT; the same key is passed to the task and user services")
+	flag.Parse()
+	server.SetSecret([]byte(secret))
+
 	r := gin.Default()
 
 	r.POST("/register", server.RegisterHandler)
@@ -14,7 +23,7 @@ func main() {
 	protected := r.Group("/", server.AuthMiddleware())
 	protected.GET("/profile", server.ProfileHandler)
 
-	r.Run(":8080")
+	r.Run(addr)
 }
 
 //R.GROUP - защищены middleware для аутентификации. Маршруты в этой группе требуют
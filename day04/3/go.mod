@@ -3,7 +3,7 @@ module github.com/lahnasti/GO_praktikum
 go 1.22.3
 
 require (
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
 	github.com/gin-gonic/gin v1.10.0
 )
 
@@ -35,3 +35,7 @@ require (
 	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+require github.com/lahnasti/GO_praktikum/day04/shared v0.0.0
+
+replace github.com/lahnasti/GO_praktikum/day04/shared => ../shared
@@ -4,52 +4,29 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/lahnasti/GO_praktikum/day04/shared/auth"
 	"github.com/lahnasti/GO_praktikum/internal/repository"
 	"github.com/lahnasti/GO_praktikum/internal/domain/models"
 )
 
-var jwtSecret = []byte("your_secret_key")
+// Ключ подписи токенов, задаётся при запуске
+var jwtSecret []byte
 
-func GenerateJWT(username string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(time.Hour * 1).Unix(),
-	})
+// Срок действия выданного токена
+const tokenTTL = time.Hour
 
-	return token.SignedString(jwtSecret)
+func SetSecret(secret []byte) {
+	jwtSecret = secret
 }
 
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
-		}
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
+// Токен по общему для сервисов day04 договору: HS256, имя пользователя в claim username
+func GenerateJWT(username string) (string, error) {
+	return auth.Issue(jwtSecret, username, tokenTTL)
+}
 
-		c.Set("username", claims["username"])
-		c.Next()
-	}
+func AuthMiddleware() gin.HandlerFunc {
+	return auth.Middleware(jwtSecret)
 }
 
 func RegisterHandler(c *gin.Context) {
@@ -91,8 +68,7 @@ func LoginHandler(c *gin.Context) {
 }
 
 func ProfileHandler(c *gin.Context) {
-	username, _ := c.Get("username")
-	user, err := repository.GetUser(username.(string))
+	user, err := repository.GetUser(auth.Username(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve user information"})
 		return
@@ -0,0 +1,147 @@
+// Пакет auth - общий для сервисов day04 договор о JWT. Сервис авторизации (day04/3)
+// выпускает токены через Issue, задачи (day04/1) и пользователи (day04/2) проверяют
+// их через Middleware. Токен подписан HS256, имя пользователя - в claim username,
+// как выдавал сервис авторизации с самого начала. Issue дублирует его в стандартный
+// sub, Verify принимает токен с любым из двух
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Claim с именем пользователя
+	ClaimUsername = "username"
+	// Стандартный subject токена, дублирует ClaimUsername
+	ClaimSubject = "sub"
+	// Ключ контекста gin, под которым Middleware оставляет имя пользователя
+	UsernameKey = "username"
+	// Параметр запроса с токеном для QueryMiddleware
+	QueryParam = "access_token"
+)
+
+// Выпускает токен пользователя username, действующий ttl
+func Issue(secret []byte, username string, ttl time.Duration) (string, error) {
+	if username == "" {
+		return "", errors.New("empty username")
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		ClaimUsername: username,
+		ClaimSubject:  username,
+		"iat":         now.Unix(),
+		"exp":         now.Add(ttl).Unix(),
+	})
+	return token.SignedString(secret)
+}
+
+// Имя пользователя из токена: подпись HS256 ключом secret, срок действия и
+// непустой username (или sub, если username нет). Ошибка - токен недействителен
+func Verify(secret []byte, tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil || !token.Valid {
+		return "", errors.New("invalid token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", errors.New("invalid token")
+	}
+	// Без exp jwt-go считает токен бессрочным
+	if _, ok := claims["exp"]; !ok {
+		return "", errors.New("token has no expiry")
+	}
+	username, _ := claims[ClaimUsername].(string)
+	if username == "" {
+		username, _ = claims[ClaimSubject].(string)
+	}
+	if username == "" {
+		return "", errors.New("token has no username")
+	}
+	return username, nil
+}
+
+// Проверяет JWT из заголовка Authorization (с "Bearer " или без) и кладёт имя
+// пользователя в контекст под UsernameKey. Без действительного токена отвечает 401
+func Middleware(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Abort()
+			return
+		}
+		username, err := Verify(secret, tokenString)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+		c.Set(UsernameKey, username)
+		c.Next()
+	}
+}
+
+// Как Middleware, но без заголовка Authorization берёт токен из ?access_token=.
+// Только для потоков из браузера: EventSource и WebSocket не умеют отправлять
+// заголовки. Адрес с токеном может попасть в журналы доступа, поэтому остальные
+// маршруты принимают токен только в заголовке
+func QueryMiddleware(secret []byte) gin.HandlerFunc {
+	required := Middleware(secret)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query(QueryParam); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		required(c)
+	}
+}
+
+// Как Middleware, но пропускает запросы без заголовка Authorization: имени в
+// контексте тогда нет. Недействительный токен - по-прежнему 401
+func Optional(secret []byte) gin.HandlerFunc {
+	required := Middleware(secret)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		required(c)
+	}
+}
+
+// Пускает только пользователей из списка admins; ставится после Middleware.
+// Пустой список не пускает никого
+func AdminOnly(admins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(admins) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin access is disabled: -admins is not set"})
+			c.Abort()
+			return
+		}
+		if !slices.Contains(admins, Username(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Имя пользователя, проверенное Middleware. Пусто - запрос без авторизации
+func Username(c *gin.Context) string {
+	return c.GetString(UsernameKey)
+}
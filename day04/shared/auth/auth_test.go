@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+var secret = []byte("test-secret")
+
+func TestIssueVerify(t *testing.T) {
+	token, err := Issue(secret, "alice", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, err := Verify(secret, token)
+	if err != nil || username != "alice" {
+		t.Fatalf("Verify = %q, %v; want alice", username, err)
+	}
+	if _, err := Verify([]byte("other"), token); err == nil {
+		t.Error("token signed with another key is accepted")
+	}
+}
+
+// Токены в обоих видах: с username, как выдавал сервис авторизации раньше, и только с sub
+func TestVerifyClaims(t *testing.T) {
+	exp := time.Now().Add(time.Minute).Unix()
+	for name, claims := range map[string]jwt.MapClaims{
+		"username": {ClaimUsername: "alice", "exp": exp},
+		"sub":      {ClaimSubject: "alice", "exp": exp},
+		"both":     {ClaimUsername: "alice", ClaimSubject: "other", "exp": exp},
+	} {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if username, err := Verify(secret, token); err != nil || username != "alice" {
+			t.Errorf("%s: Verify = %q, %v; want alice", name, username, err)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	expired, _ := Issue(secret, "alice", -time.Minute)
+	noExpiry, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{ClaimSubject: "alice"}).SignedString(secret)
+	noUsername, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()}).SignedString(secret)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		ClaimSubject: "alice", "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	for name, token := range map[string]string{
+		"expired": expired, "no expiry": noExpiry, "no username": noUsername, "alg none": unsigned, "garbage": "abc",
+	} {
+		if username, err := Verify(secret, token); err == nil {
+			t.Errorf("%s: Verify = %q, want error", name, username)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", Middleware(secret), func(c *gin.Context) {
+		c.String(http.StatusOK, Username(c))
+	})
+	token, _ := Issue(secret, "bob", time.Minute)
+	for _, tc := range []struct {
+		header string
+		code   int
+		body   string
+	}{
+		{"Bearer " + token, http.StatusOK, "bob"},
+		{token, http.StatusOK, "bob"},
+		{"", http.StatusUnauthorized, ""},
+		{"Bearer nope", http.StatusUnauthorized, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || tc.code == http.StatusOK && w.Body.String() != tc.body {
+			t.Errorf("Authorization %q: got %d %q, want %d %q", tc.header, w.Code, w.Body.String(), tc.code, tc.body)
+		}
+	}
+}
+
+func TestOptional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", Optional(secret), func(c *gin.Context) {
+		c.String(http.StatusOK, Username(c))
+	})
+	token, _ := Issue(secret, "bob", time.Minute)
+	for _, tc := range []struct {
+		header string
+		code   int
+		body   string
+	}{
+		{"Bearer " + token, http.StatusOK, "bob"},
+		{"", http.StatusOK, ""},
+		{"Bearer nope", http.StatusUnauthorized, ""},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || tc.code == http.StatusOK && w.Body.String() != tc.body {
+			t.Errorf("Authorization %q: got %d %q, want %d %q", tc.header, w.Code, w.Body.String(), tc.code, tc.body)
+		}
+	}
+}
+
+func TestQueryMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stream", QueryMiddleware(secret), func(c *gin.Context) {
+		c.String(http.StatusOK, Username(c))
+	})
+	token, _ := Issue(secret, "bob", time.Minute)
+	for _, tc := range []struct {
+		target, header string
+		code           int
+	}{
+		{"/stream?" + QueryParam + "=" + token, "", http.StatusOK},
+		{"/stream", "Bearer " + token, http.StatusOK},
+		{"/stream", "", http.StatusUnauthorized},
+		{"/stream?" + QueryParam + "=nope", "", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || tc.code == http.StatusOK && w.Body.String() != "bob" {
+			t.Errorf("%s: got %d %q, want %d", tc.target, w.Code, w.Body.String(), tc.code)
+		}
+	}
+}
+
+func TestAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	alice, _ := Issue(secret, "alice", time.Minute)
+	bob, _ := Issue(secret, "bob", time.Minute)
+	for _, tc := range []struct {
+		admins []string
+		token  string
+		code   int
+	}{
+		{[]string{"alice"}, alice, http.StatusOK},
+		{[]string{"alice"}, bob, http.StatusForbidden},
+		{nil, alice, http.StatusForbidden},
+	} {
+		r := gin.New()
+		r.GET("/", Middleware(secret), AdminOnly(tc.admins), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("admins %v: got %d, want %d", tc.admins, w.Code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Пакет webhook рассылает события сервисов day04 подписчикам: каждое событие
+// записывается в журнал доставок для всех подходящих вебхуков, а Dispatcher
+// отправляет их, подписывая HMAC-SHA256 и повторяя неудачные попытки.
+// Типы событий задаёт каждый сервис сам
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Подписка на все события
+const AllEvents = "*"
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// sha256=<hex HMAC-SHA256 от "<timestamp>.<тело>">
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// Сколько доставок забирать за один проход и сколько отправлять одновременно
+	batchSize   = 50
+	parallelism = 8
+	// Потолок паузы между попытками
+	maxBackoff = 6 * time.Hour
+)
+
+type Store interface {
+	// Записывает доставки события на все вебхуки, подписанные на него. Возвращает их число
+	EnqueueEvent(event string, payload []byte) (int, error)
+	// Забирает доставки, которым пора уходить, и откладывает их на lease,
+	// чтобы другой экземпляр сервиса не отправил их одновременно
+	ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	GetWebhook(id string) (Webhook, error)
+	// Сохраняет результат попытки: статус, число попыток, время следующей
+	FinishDelivery(delivery Delivery) error
+}
+
+type Config struct {
+	// Как часто проверять очередь доставок
+	Interval time.Duration
+	// Тайм-аут одного запроса к подписчику
+	Timeout time.Duration
+	// Пауза после первой неудачи; дальше удваивается с каждой попыткой
+	Backoff time.Duration
+	// После стольких неудачных попыток доставка считается проваленной
+	MaxAttempts int
+	// Куда можно отправлять события
+	Targets Targets
+}
+
+// Конверт события - тело запроса к подписчику
+type envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Dispatcher struct {
+	store  Store
+	cfg    Config
+	client *http.Client
+	// Будит Run, когда появились новые доставки
+	wake chan struct{}
+	log  *zerolog.Logger
+}
+
+func New(store Store, cfg Config, zlog *zerolog.Logger) *Dispatcher {
+	// Адрес проверяется при каждом соединении, переадресации не выполняются:
+	// иначе ответ 3xx мог бы увести запрос во внутреннюю сеть. Прокси из окружения
+	// не используется - проверялся бы его адрес, а не подписчика
+	dialer := &net.Dialer{Timeout: cfg.Timeout, Control: cfg.Targets.control}
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.Timeout,
+			MaxIdleConnsPerHost: parallelism,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Dispatcher{
+		store:  store,
+		cfg:    cfg,
+		client: client,
+		wake:   make(chan struct{}, 1),
+		log:    zlog,
+	}
+}
+
+// Можно ли отправлять события на адрес rawURL - см. Targets.Check
+func (d *Dispatcher) CheckURL(rawURL string) error {
+	return d.cfg.Targets.Check(rawURL)
+}
+
+// Ставит событие в очередь доставки подписчикам. Ошибки только пишутся в лог:
+// операция, породившая событие, уже выполнена
+func (d *Dispatcher) Emit(event string, data any) {
+	payload, err := json.Marshal(envelope{
+		ID:        uuid.New().String(),
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		d.log.Error().Err(err).Str("event", event).Msg("Failed to encode webhook event")
+		return
+	}
+	n, err := d.store.EnqueueEvent(event, payload)
+	if err != nil {
+		d.log.Error().Err(err).Str("event", event).Msg("Failed to enqueue webhook event")
+		return
+	}
+	if n > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run работает до отмены ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		d.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	// Пока доставка в работе, её не заберёт никто другой; запас на случай медленного сохранения
+	lease := d.cfg.Timeout + time.Minute
+	for ctx.Err() == nil {
+		deliveries, err := d.store.ClaimDeliveries(time.Now().UTC(), lease, batchSize)
+		if err != nil {
+			d.log.Error().Err(err).Msg("Failed to claim webhook deliveries")
+			return
+		}
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, parallelism)
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.deliver(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+// Одна попытка доставки и сохранение её результата
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	hook, err := d.store.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		// Вебхук удалён вместе с доставками - сохранять нечего
+		d.log.Error().Err(err).Str("delivery_id", delivery.ID).Msg("Failed to get webhook")
+		return
+	}
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseCode, err = d.send(ctx, hook, delivery, now)
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = DeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+	default:
+		next := now.Add(Backoff(d.cfg.Backoff, delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	}
+	if err != nil {
+		d.log.Warn().Err(err).Str("delivery_id", delivery.ID).Int("attempt", delivery.Attempts).Msg("Webhook delivery failed")
+	}
+	if err := d.store.FinishDelivery(delivery); err != nil {
+		d.log.Error().Err(err).Str("delivery_id", delivery.ID).Msg("Failed to save webhook delivery")
+	}
+}
+
+// Отправляет доставку; успех - любой ответ 2xx. Возвращает код ответа (0, если ответа нет)
+func (d *Dispatcher) send(ctx context.Context, hook Webhook, delivery Delivery, now time.Time) (int, error) {
+	// Ограничения могли ужесточить после создания подписки
+	if err := d.CheckURL(hook.URL); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GO_praktikum-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, delivery.Payload))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Дочитываем немного, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Подпись доставки: sha256=<hex HMAC-SHA256 от "<timestamp>.<тело>">.
+// Метка времени в подписи не даёт переиграть старый запрос
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Пауза перед попыткой attempt+1: base, 2*base, 4*base... но не больше maxBackoff
+func Backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// Подписка на события: на какой адрес и какие события отправлять
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url" validate:"required,url"`
+	// Типы событий сервиса (например, task.created или user.registered); "*" - все события
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+	// Ключ подписи HMAC-SHA256. Отдаётся только при создании подписки
+	Secret    string    `json:"secret,omitempty" validate:"omitempty,min=16,max=200"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Состояние доставки события
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// Попытки исчерпаны; доставку можно повторить вручную
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Доставка события на один вебхук - запись журнала доставок
+type Delivery struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhook_id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    DeliveryStatus  `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// Когда следующая попытка; пусто, когда доставка завершена
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// Результат последней попытки
+	ResponseCode int        `json:"response_code,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Сколько последних доставок вебхука отдавать в журнале
+const deliveryLogLimit = 100
+
+// Вебхуки и журнал доставок в памяти
+type Storage struct {
+	mu         sync.RWMutex
+	webhooks   map[string]Webhook
+	deliveries map[string]Delivery
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		webhooks:   make(map[string]Webhook),
+		deliveries: make(map[string]Delivery),
+	}
+}
+
+func (stor *Storage) AddWebhook(hook Webhook) (Webhook, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	hook.ID = uuid.New().String()
+	hook.CreatedAt = time.Now().UTC()
+	stor.webhooks[hook.ID] = hook
+	return hook, nil
+}
+
+// Вебхуки в порядке создания
+func (stor *Storage) GetWebhooks() ([]Webhook, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	hooks := []Webhook{}
+	for _, hook := range stor.webhooks {
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		if !hooks[i].CreatedAt.Equal(hooks[j].CreatedAt) {
+			return hooks[i].CreatedAt.Before(hooks[j].CreatedAt)
+		}
+		return hooks[i].ID < hooks[j].ID
+	})
+	return hooks, nil
+}
+
+func (stor *Storage) GetWebhook(id string) (Webhook, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	hook, exists := stor.webhooks[id]
+	if !exists {
+		return Webhook{}, ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// Удаляет вебхук вместе с журналом его доставок
+func (stor *Storage) DeleteWebhook(id string) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+	delete(stor.webhooks, id)
+	for deliveryID, delivery := range stor.deliveries {
+		if delivery.WebhookID == id {
+			delete(stor.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// Последние доставки вебхука: сначала новые
+func (stor *Storage) GetDeliveries(webhookID string) ([]Delivery, error) {
+	stor.mu.RLock()
+	defer stor.mu.RUnlock()
+	if _, exists := stor.webhooks[webhookID]; !exists {
+		return nil, ErrWebhookNotFound
+	}
+	deliveries := []Delivery{}
+	for _, delivery := range stor.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	return deliveries[:min(len(deliveries), deliveryLogLimit)], nil
+}
+
+// Ставит в очередь новую доставку с тем же событием. Исходная запись журнала не меняется
+func (stor *Storage) Redeliver(webhookID, deliveryID string) (Delivery, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	orig, exists := stor.deliveries[deliveryID]
+	if !exists || orig.WebhookID != webhookID {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	delivery := stor.newDelivery(webhookID, orig.Event, orig.Payload)
+	return delivery, nil
+}
+
+func (stor *Storage) EnqueueEvent(event string, payload []byte) (int, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	n := 0
+	for id, hook := range stor.webhooks {
+		if slices.Contains(hook.Events, event) || slices.Contains(hook.Events, AllEvents) {
+			stor.newDelivery(id, event, payload)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (stor *Storage) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	due := []Delivery{}
+	for _, delivery := range stor.deliveries {
+		if delivery.Status == DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+	due = due[:min(len(due), limit)]
+	until := now.Add(lease)
+	for _, delivery := range due {
+		delivery.NextAttemptAt = &until
+		stor.deliveries[delivery.ID] = delivery
+	}
+	return due, nil
+}
+
+func (stor *Storage) FinishDelivery(delivery Delivery) error {
+	stor.mu.Lock()
+	defer stor.mu.Unlock()
+	if _, exists := stor.deliveries[delivery.ID]; !exists {
+		return ErrDeliveryNotFound
+	}
+	stor.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+// Вызывается под stor.mu
+func (stor *Storage) newDelivery(webhookID, event string, payload []byte) Delivery {
+	now := time.Now().UTC()
+	delivery := Delivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	stor.deliveries[delivery.ID] = delivery
+	return delivery
+}
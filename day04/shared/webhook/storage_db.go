@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Вебхуки и журнал доставок в Postgres. Таблицы webhooks и webhook_deliveries
+// создаёт своя миграция каждого сервиса: у сервисов разные базы
+type DBstorage struct {
+	conn *pgxpool.Pool
+}
+
+func NewDBStorage(conn *pgxpool.Pool) DBstorage {
+	return DBstorage{
+		conn: conn,
+	}
+}
+
+const webhookColumns = "id, url, events, secret, created_by, created_at"
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at,
+	coalesce(response_code, 0), coalesce(last_error, ''), created_at, delivered_at`
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var w Webhook
+	err := row.Scan(&w.ID, &w.URL, &w.Events, &w.Secret, &w.CreatedBy, &w.CreatedAt)
+	return w, err
+}
+
+func scanDelivery(row pgx.Row) (Delivery, error) {
+	var d Delivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	return d, err
+}
+
+func (db *DBstorage) AddWebhook(hook Webhook) (Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	created, err := scanWebhook(db.conn.QueryRow(ctx, `INSERT INTO webhooks (url, events, secret, created_by)
+		VALUES ($1, $2, $3, $4) RETURNING `+webhookColumns, hook.URL, hook.Events, hook.Secret, hook.CreatedBy))
+	if err != nil {
+		return Webhook{}, fmt.Errorf("add webhook failed: %w", err)
+	}
+	return created, nil
+}
+
+// Вебхуки в порядке создания
+func (db *DBstorage) GetWebhooks() ([]Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hooks := []Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (db *DBstorage) GetWebhook(id string) (Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hook, err := scanWebhook(db.conn.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		return Webhook{}, webhookError(err, ErrWebhookNotFound)
+	}
+	return hook, nil
+}
+
+// Удаляет вебхук; журнал его доставок удаляется каскадом
+func (db *DBstorage) DeleteWebhook(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return webhookError(err, ErrWebhookNotFound)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Последние доставки вебхука: сначала новые
+func (db *DBstorage) GetDeliveries(webhookID string) ([]Delivery, error) {
+	if _, err := db.GetWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, "SELECT "+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, webhookID, deliveryLogLimit)
+	if err != nil {
+		return nil, err
+	}
+	return collectDeliveries(rows)
+}
+
+// Ставит в очередь новую доставку с тем же событием. Исходная запись журнала не меняется
+func (db *DBstorage) Redeliver(webhookID, deliveryID string) (Delivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	delivery, err := scanDelivery(db.conn.QueryRow(ctx, `INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT webhook_id, event, payload FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2
+		RETURNING `+deliveryColumns, deliveryID, webhookID))
+	if err != nil {
+		return Delivery{}, webhookError(err, ErrDeliveryNotFound)
+	}
+	return delivery, nil
+}
+
+func (db *DBstorage) EnqueueEvent(event string, payload []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tag, err := db.conn.Exec(ctx, `INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $1, $2::jsonb FROM webhooks WHERE $1 = ANY(events) OR '*' = ANY(events)`, event, payload)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue webhook event failed: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Забирает доставки, которым пора уходить. SKIP LOCKED позволяет нескольким
+// экземплярам сервиса разбирать очередь, не мешая друг другу
+func (db *DBstorage) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.conn.Query(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries failed: %w", err)
+	}
+	return collectDeliveries(rows)
+}
+
+func (db *DBstorage) FinishDelivery(delivery Delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var code *int
+	if delivery.ResponseCode != 0 {
+		code = &delivery.ResponseCode
+	}
+	var lastError *string
+	if delivery.LastError != "" {
+		lastError = &delivery.LastError
+	}
+	tag, err := db.conn.Exec(ctx, `UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, response_code = $5, last_error = $6, delivered_at = $7
+		WHERE id = $1`, delivery.ID, string(delivery.Status), delivery.Attempts, delivery.NextAttemptAt,
+		code, lastError, delivery.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("finish webhook delivery failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func collectDeliveries(rows pgx.Rows) ([]Delivery, error) {
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Нет строки или неверный UUID - notFound
+func webhookError(err, notFound error) error {
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+		return notFound
+	}
+	return err
+}
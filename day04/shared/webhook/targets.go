@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var ErrForbiddenTarget = errors.New("webhook target is not allowed")
+
+// Куда разрешено отправлять события. Без ограничений подписчик мог бы
+// заставить сервис обращаться к его внутренним адресам (SSRF)
+type Targets struct {
+	// Разрешённые хосты: имя целиком или ".example.com" для всех поддоменов. Пусто - любые
+	Hosts []string
+	// Разрешить адреса loopback, частных сетей и link-local - например, для разработки
+	AllowPrivate bool
+}
+
+// Проверяет адрес подписки: схема http или https, без учётных данных, хост
+// из списка разрешённых и не внутренний IP-адрес. Имена хостов проверяются
+// ещё раз при соединении, когда уже известен адрес
+func (t Targets) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenTarget, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrForbiddenTarget)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in URL", ErrForbiddenTarget)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: empty host", ErrForbiddenTarget)
+	}
+	if !t.hostAllowed(host) {
+		return fmt.Errorf("%w: host %s is not in the allowed list", ErrForbiddenTarget, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !t.addrAllowed(addr) {
+		return fmt.Errorf("%w: internal address %s", ErrForbiddenTarget, addr)
+	}
+	return nil
+}
+
+func (t Targets) hostAllowed(host string) bool {
+	if len(t.Hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range t.Hosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Targets) addrAllowed(addr netip.Addr) bool {
+	if t.AllowPrivate {
+		return true
+	}
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnat.Contains(addr)
+}
+
+// Общее адресное пространство провайдеров (RFC 6598): IsPrivate его не включает
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// Проверка адреса перед соединением (net.Dialer.Control): имя уже разрешено,
+// поэтому подмена DNS после создания подписки не ведёт во внутреннюю сеть
+func (t Targets) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenTarget, err)
+	}
+	if !t.addrAllowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: internal address %s", ErrForbiddenTarget, addrPort.Addr())
+	}
+	return nil
+}